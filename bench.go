@@ -0,0 +1,137 @@
+package reql
+
+import (
+	"math"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// BenchOptions controls how a request is benchmarked. The benchmark stops once
+// Requests requests have been sent or Duration has elapsed, whichever comes first. A
+// zero value disables the corresponding limit, but at least one must be set.
+type BenchOptions struct {
+	Requests    int
+	Duration    time.Duration
+	Concurrency int
+}
+
+// BenchReport holds the results of a benchmark.
+type BenchReport struct {
+	// Requests is the number of requests that were sent, including failed ones.
+	Requests int
+	// Elapsed is the wall clock time the benchmark took.
+	Elapsed time.Duration
+	// StatusCodes counts the responses by status code.
+	StatusCodes map[int]int
+	// Errors counts the requests that failed without a response by error message.
+	Errors map[string]int
+	// Latencies holds the latency of every request that received a response in
+	// ascending order.
+	Latencies []time.Duration
+}
+
+// Bench sends req repeatedly using client from opts.Concurrency goroutines and
+// reports the results.
+func Bench(client *Client, req Request, opts BenchOptions) *BenchReport {
+	workers := opts.Concurrency
+	if workers < 1 {
+		workers = 1
+	}
+
+	var deadline time.Time
+	if opts.Duration > 0 {
+		deadline = time.Now().Add(opts.Duration)
+	}
+
+	report := &BenchReport{
+		StatusCodes: make(map[int]int),
+		Errors:      make(map[string]int),
+	}
+
+	var (
+		mu     sync.Mutex
+		wg     sync.WaitGroup
+		issued int64
+	)
+
+	start := time.Now()
+	for i := 0; i < workers; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			for {
+				if opts.Requests > 0 && atomic.AddInt64(&issued, 1) > int64(opts.Requests) {
+					return
+				} else if !deadline.IsZero() && time.Now().After(deadline) {
+					return
+				}
+
+				ex, err := client.Do(req)
+
+				mu.Lock()
+				report.Requests++
+				if err != nil {
+					report.Errors[err.Error()]++
+				} else {
+					report.StatusCodes[ex.Response.StatusCode]++
+					report.Latencies = append(report.Latencies, ex.Timing.Total)
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	report.Elapsed = time.Since(start)
+	sort.Slice(report.Latencies, func(i, j int) bool { return report.Latencies[i] < report.Latencies[j] })
+
+	return report
+}
+
+// ErrorCount returns the number of requests that failed without a response.
+func (r *BenchReport) ErrorCount() int {
+	n := 0
+	for _, count := range r.Errors {
+		n += count
+	}
+
+	return n
+}
+
+// Throughput returns the number of requests sent per second.
+func (r *BenchReport) Throughput() float64 {
+	if r.Elapsed <= 0 {
+		return 0
+	}
+
+	return float64(r.Requests) / r.Elapsed.Seconds()
+}
+
+// Percentile returns the latency that p percent of the responses were at least as
+// fast as using the nearest-rank method. p must be in the range (0, 100].
+func (r *BenchReport) Percentile(p float64) time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	rank := int(math.Ceil(p / 100 * float64(len(r.Latencies))))
+	if rank < 1 {
+		rank = 1
+	} else if rank > len(r.Latencies) {
+		rank = len(r.Latencies)
+	}
+
+	return r.Latencies[rank-1]
+}
+
+// Max returns the slowest latency.
+func (r *BenchReport) Max() time.Duration {
+	if len(r.Latencies) == 0 {
+		return 0
+	}
+
+	return r.Latencies[len(r.Latencies)-1]
+}
@@ -0,0 +1,89 @@
+package reql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+)
+
+func TestBenchReport_Percentile(t *testing.T) {
+	report := &BenchReport{}
+	for i := 1; i <= 100; i++ {
+		report.Latencies = append(report.Latencies, time.Duration(i)*time.Millisecond)
+	}
+
+	tests := []struct {
+		name string
+		p    float64
+		want time.Duration
+	}{
+		{
+			name: "p50",
+			p:    50,
+			want: 50 * time.Millisecond,
+		},
+		{
+			name: "p90",
+			p:    90,
+			want: 90 * time.Millisecond,
+		},
+		{
+			name: "p99",
+			p:    99,
+			want: 99 * time.Millisecond,
+		},
+		{
+			name: "p100",
+			p:    100,
+			want: 100 * time.Millisecond,
+		},
+		{
+			name: "Tiny percentile",
+			p:    0.001,
+			want: time.Millisecond,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := report.Percentile(tt.p); got != tt.want {
+				t.Errorf("BenchReport.Percentile() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := (&BenchReport{}).Percentile(50); got != 0 {
+		t.Errorf("BenchReport.Percentile() with no latencies = %v, want 0", got)
+	}
+}
+
+func TestBench(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Query().Get("fail") != "" {
+			w.WriteHeader(http.StatusTeapot)
+		}
+	}))
+	defer srv.Close()
+
+	report := Bench(mustNewClient(t), Request{Method: http.MethodGet, URL: srv.URL + "?fail=1"}, BenchOptions{
+		Requests:    25,
+		Concurrency: 4,
+	})
+
+	if report.Requests != 25 {
+		t.Errorf("Bench() sent %d requests, want 25", report.Requests)
+	}
+	if report.StatusCodes[http.StatusTeapot] != 25 {
+		t.Errorf("Bench() status codes = %v, want 25 x 418", report.StatusCodes)
+	}
+	if len(report.Latencies) != 25 || report.Max() < report.Percentile(50) {
+		t.Errorf("Bench() recorded %d latencies, want 25 in ascending order", len(report.Latencies))
+	}
+
+	report = Bench(mustNewClient(t), Request{Method: http.MethodGet, URL: "http://127.0.0.1:0"}, BenchOptions{
+		Requests: 3,
+	})
+	if report.ErrorCount() != 3 {
+		t.Errorf("Bench() counted %d errors, want 3", report.ErrorCount())
+	}
+}
@@ -2,35 +2,56 @@ package cli
 
 import (
 	"bufio"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
-	"net/http"
 	"os"
 	"path/filepath"
+	"sort"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/mattmeyers/repl"
 	"github.com/mattmeyers/reql"
 	"github.com/urfave/cli/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
 )
 
 type App struct {
 	reader *bufio.Reader
 	writer io.Writer
-	logger reql.Logger
+	// errWriter receives reports without a path when writer holds machine readable
+	// output.
+	errWriter io.Writer
+	logger    reql.Logger
 
 	args   []string
 	config *reql.Config
+	client *reql.Client
 	env    string
+	vars   *reql.Vars
 	app    *cli.App
+
+	// raw disables formatting of response bodies.
+	raw bool
+	// color enables ANSI colors, which are only used when writing to a terminal.
+	color bool
+	// secrets are the secret values of the current env, masked in output.
+	secrets []string
+	// quitWarned is set once the REPL warned about unsaved env changes on quit.
+	quitWarned bool
 }
 
 func New(args []string) *App {
 	a := &App{
-		reader: bufio.NewReader(os.Stdin),
-		writer: os.Stdout,
-		args:   args,
+		reader:    bufio.NewReader(os.Stdin),
+		writer:    os.Stdout,
+		errWriter: os.Stderr,
+		args:      args,
+		vars:      reql.NewVars(),
 	}
 
 	a.app = &cli.App{
@@ -40,8 +61,7 @@ func New(args []string) *App {
 			&cli.PathFlag{
 				Name:      "config",
 				Aliases:   []string{"c"},
-				Usage:     "Point to a reqlrc config file",
-				Value:     "./.reqlrc",
+				Usage:     "Point to a .reqrc config file instead of searching the working directory and its parents",
 				TakesFile: true,
 			},
 			&cli.BoolFlag{
@@ -54,17 +74,22 @@ func New(args []string) *App {
 				Aliases: []string{"vv"},
 				Usage:   "Log debug information information",
 			},
+			&cli.BoolFlag{
+				Name:  "raw",
+				Usage: "Print response bodies as received without formatting or colors",
+			},
 		},
 		Before: func(c *cli.Context) error {
 			var err error
 
-			reqlrcPath := c.Path("config")
-			a.config, err = reql.ParseConfig(reqlrcPath)
+			a.config, err = reql.LoadConfig(c.Path("config"))
 			if err != nil {
 				return err
 			}
 
 			a.env = a.config.DefaultEnv
+			a.raw = c.Bool("raw")
+			a.color = !a.raw && isTerminal(a.writer)
 
 			level := reql.LevelWarn
 			if c.Bool("verbose") {
@@ -73,43 +98,120 @@ func New(args []string) *App {
 				level = reql.LevelDebug
 			}
 
-			a.logger, err = reql.NewLevelLogger(level, os.Stdout)
+			logger, err := reql.NewLevelLogger(level, os.Stderr)
 			if err != nil {
 				return err
 			}
+			a.logger = maskingLogger{Logger: logger, mask: a.mask}
+
+			// The config commands report problems with the config themselves.
+			if c.Args().First() == "config" {
+				return nil
+			}
+
+			for _, err := range a.config.Validate() {
+				a.logger.Warn("%v", err)
+			}
+
+			a.client, err = reql.NewClient(a.config.Client)
+			if err != nil {
+				return fmt.Errorf("invalid client configuration: %v", err)
+			}
 
 			return nil
 		},
 		Action: a.handleReplCommand,
 		Commands: []*cli.Command{
 			{
-				Name:  "send",
-				Usage: "Send a request by alias or glob",
-				Before: func(c *cli.Context) error {
-					if env := c.String("env"); env != "" {
-						if _, ok := a.config.Environments[env]; !ok {
-							return errors.New("unknown env")
-						}
-
-						a.env = env
-					}
-
-					return nil
-				},
+				Name:   "send",
+				Usage:  "Send a request by alias or glob",
+				Before: a.selectEnv,
 				Flags: []cli.Flag{
 					&cli.StringFlag{
 						Name:    "env",
 						Aliases: []string{"e"},
 						Usage:   "Select the env to use",
 					},
+					&cli.IntFlag{
+						Name:    "parallel",
+						Aliases: []string{"p"},
+						Usage:   "Send up to `N` independent requests at the same time",
+						Value:   1,
+					},
+					&cli.StringFlag{
+						Name:    "output",
+						Aliases: []string{"o"},
+						Usage:   "Print results as `FORMAT`: text, json, or jsonl",
+						Value:   outputText,
+					},
+					&cli.StringSliceFlag{
+						Name:  "report",
+						Usage: "Write an assertion report as `FORMAT[=PATH]`: junit or tap. Without a path, the report is printed",
+					},
 				},
 				Action: a.handleSendCommand,
 			},
+			{
+				Name:      "test",
+				Usage:     "Run every reqfile under the root directory as a test suite",
+				ArgsUsage: "[dir]",
+				Before:    a.selectEnv,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "env",
+						Aliases: []string{"e"},
+						Usage:   "Select the env to use",
+					},
+					&cli.IntFlag{
+						Name:    "parallel",
+						Aliases: []string{"p"},
+						Usage:   "Run up to `N` independent reqfiles at the same time",
+						Value:   1,
+					},
+					&cli.StringSliceFlag{
+						Name:  "report",
+						Usage: "Write an assertion report as `FORMAT[=PATH]`: junit or tap. Without a path, the report is printed",
+					},
+				},
+				Action: a.handleTestCommand,
+			},
+			{
+				Name:      "bench",
+				Usage:     "Repeatedly send a request by alias or glob and report latency statistics",
+				ArgsUsage: "{alias|glob}",
+				Before:    a.selectEnv,
+				Flags: []cli.Flag{
+					&cli.StringFlag{
+						Name:    "env",
+						Aliases: []string{"e"},
+						Usage:   "Select the env to use",
+					},
+					&cli.IntFlag{
+						Name:    "requests",
+						Aliases: []string{"n"},
+						Usage:   "Send `N` requests in total (default: 100 when no duration is set)",
+					},
+					&cli.DurationFlag{
+						Name:    "duration",
+						Aliases: []string{"d"},
+						Usage:   "Send requests for this long, e.g. 30s",
+					},
+					&cli.IntFlag{
+						Name:    "concurrency",
+						Aliases: []string{"C"},
+						Usage:   "Keep `N` requests in flight at the same time",
+						Value:   1,
+					},
+				},
+				Action: a.handleBenchCommand,
+			},
 			{
 				Name:   "list",
 				Usage:  "List all available requests",
 				Action: a.handleListCommand,
 			},
+			a.secretCommand(),
+			a.configCommand(),
 		},
 	}
 
@@ -131,12 +233,12 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 				return "", repl.ErrNoMatch
 			}
 
-			command := strings.SplitN(c.Input, " ", 2)
-			if len(command) != 2 {
-				return "", repl.NewError("alias or glob required")
+			glob, opts, err := parseSendArgs(strings.Fields(c.Input)[1:])
+			if err != nil {
+				return "", repl.NewError(err.Error())
 			}
 
-			err := a.handleSend(command[1])
+			err = a.handleSend(glob, opts)
 			if err != nil {
 				return "", repl.NewError(err.Error())
 			}
@@ -172,12 +274,34 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 				return "", repl.ErrNoMatch
 			}
 
-			for k, v := range a.config.Environments[a.env] {
-				fmt.Fprintf(a.writer, "%s = %s\n", k, v)
+			err := a.printEnv()
+			if err != nil {
+				return "", repl.NewError(err.Error())
+			}
+
+			return "", nil
+		}).
+		WithHandler(func(c *repl.Context) (string, error) {
+			if c.Input != "vars" {
+				return "", repl.ErrNoMatch
+			}
+
+			err := a.printVars()
+			if err != nil {
+				return "", repl.NewError(err.Error())
 			}
 
 			return "", nil
 		}).
+		WithHandler(func(c *repl.Context) (string, error) {
+			if c.Input != "vars-clear" {
+				return "", repl.ErrNoMatch
+			}
+
+			a.vars.Clear()
+
+			return "", nil
+		}).
 		WithHandler(func(c *repl.Context) (string, error) {
 			if c.Input != "help" && c.Input != "h" {
 				return "", repl.ErrNoMatch
@@ -220,6 +344,9 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 			if err != nil {
 				return "", repl.NewError(err.Error())
 			}
+			if err := a.autosave(); err != nil {
+				return "", repl.NewError(err.Error())
+			}
 
 			a.env = command[1]
 
@@ -244,6 +371,9 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 			if err != nil {
 				return "", repl.NewError(err.Error())
 			}
+			if err := a.autosave(); err != nil {
+				return "", repl.NewError(err.Error())
+			}
 
 			return "", nil
 		}).
@@ -261,6 +391,20 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 			if err != nil {
 				return "", repl.NewError(err.Error())
 			}
+			if err := a.autosave(); err != nil {
+				return "", repl.NewError(err.Error())
+			}
+
+			return "", nil
+		}).
+		WithHandler(func(c *repl.Context) (string, error) {
+			if c.Input != "save" {
+				return "", repl.ErrNoMatch
+			}
+
+			if err := a.save(); err != nil {
+				return "", repl.NewError(err.Error())
+			}
 
 			return "", nil
 		}).
@@ -269,12 +413,38 @@ func (a *App) handleReplCommand(c *cli.Context) error {
 				return "", repl.ErrNoMatch
 			}
 
+			if a.config.Unsaved() && !a.quitWarned {
+				a.quitWarned = true
+				return "", repl.NewError("unsaved env changes (save them with save or quit again to discard them)")
+			}
+
 			return "", repl.ErrExit
 		})
 
 	return r.Run()
 }
 
+// save writes the config back to the file it was loaded from.
+func (a *App) save() error {
+	if err := a.config.Save(); err != nil {
+		return err
+	}
+
+	a.quitWarned = false
+	fmt.Fprintf(a.writer, "Saved %s\n", a.config.Path())
+
+	return nil
+}
+
+// autosave saves the config after an env change if autosave is enabled.
+func (a *App) autosave() error {
+	if !a.config.Autosave {
+		return nil
+	}
+
+	return a.save()
+}
+
 func (a *App) prompt(ctx *repl.Context) (string, error) {
 	prompt := ">> "
 	if a.env != "" {
@@ -290,7 +460,51 @@ func (a *App) handleSendCommand(c *cli.Context) error {
 		return nil
 	}
 
-	err := a.handleSend(c.Args().First())
+	opts := sendOptions{
+		parallel: c.Int("parallel"),
+		output:   c.String("output"),
+	}
+	var err error
+	opts.reports, err = parseReportSpecs(c.StringSlice("report"))
+	if err != nil {
+		return err
+	}
+	if err := opts.validate(); err != nil {
+		return err
+	}
+
+	return a.handleSend(c.Args().First(), opts)
+}
+
+// selectEnv switches to the env given by the --env flag, if any.
+func (a *App) selectEnv(c *cli.Context) error {
+	if env := c.String("env"); env != "" {
+		if _, ok := a.config.Environments[env]; !ok {
+			return errors.New("unknown env")
+		}
+
+		a.env = env
+	}
+
+	return nil
+}
+
+func (a *App) handleBenchCommand(c *cli.Context) error {
+	if c.Args().Len() == 0 {
+		a.logger.Error("alias or glob required")
+		return nil
+	}
+
+	opts := reql.BenchOptions{
+		Requests:    c.Int("requests"),
+		Duration:    c.Duration("duration"),
+		Concurrency: c.Int("concurrency"),
+	}
+	if opts.Requests <= 0 && opts.Duration <= 0 {
+		opts.Requests = 100
+	}
+
+	err := a.handleBench(c.Args().First(), opts)
 	if err != nil {
 		a.logger.Error(err.Error())
 	}
@@ -298,6 +512,29 @@ func (a *App) handleSendCommand(c *cli.Context) error {
 	return nil
 }
 
+// handleTestCommand runs the reqfiles under the given directory, or the configured
+// root, and returns an error whenever the suite did not pass so that the process
+// exits with a non-zero status.
+func (a *App) handleTestCommand(c *cli.Context) error {
+	reports, err := parseReportSpecs(c.StringSlice("report"))
+	if err != nil {
+		return err
+	}
+
+	root := a.config.Root
+	if c.Args().Len() > 0 {
+		root = c.Args().First()
+	} else if root == "" {
+		root = "."
+	}
+
+	return a.handleTest(root, sendOptions{
+		parallel: c.Int("parallel"),
+		output:   outputText,
+		reports:  reports,
+	})
+}
+
 func (a *App) handleListCommand(c *cli.Context) error {
 	err := a.handleList()
 	if err != nil {
@@ -307,40 +544,198 @@ func (a *App) handleListCommand(c *cli.Context) error {
 	return nil
 }
 
-func (a *App) handleSend(glob string) error {
+// sendOptions controls how reqfiles are sent and how their results are printed.
+type sendOptions struct {
+	parallel int
+	output   string
+	reports  []reportSpec
+}
+
+func (o sendOptions) validate() error {
+	switch o.output {
+	case outputText, outputJSON, outputJSONL:
+	default:
+		return fmt.Errorf("unknown output format %q", o.output)
+	}
+
+	return nil
+}
+
+// parseSendArgs parses the arguments of the REPL send command, which take the form
+// [-p N | --parallel N] {alias|glob}.
+func parseSendArgs(args []string) (string, sendOptions, error) {
+	opts := sendOptions{parallel: 1, output: outputText}
+	if len(args) > 0 && (args[0] == "-p" || args[0] == "--parallel") {
+		if len(args) < 2 {
+			return "", opts, errors.New("parallel value required")
+		}
+
+		n, err := strconv.Atoi(args[1])
+		if err != nil || n < 1 {
+			return "", opts, errors.New("parallel value must be a positive integer")
+		}
+
+		opts.parallel = n
+		args = args[2:]
+	}
+
+	if len(args) != 1 {
+		return "", opts, errors.New("alias or glob required")
+	}
+
+	return args[0], opts, nil
+}
+
+func (a *App) handleSend(glob string, opts sendOptions) error {
+	files, err := a.getFiles(glob)
+	if err != nil {
+		return fmt.Errorf("could not retrieve files: %v", err)
+	}
+
+	plan, err := reql.NewPlan(files, a.config.Aliases)
+	if err != nil {
+		return fmt.Errorf("could not order request(s): %v", err)
+	}
+
+	summary, err := a.sendRequests(plan, opts)
+	if err != nil {
+		return err
+	}
+	if !summary.passed() {
+		return fmt.Errorf("%w: %s", errSendFailed, summary)
+	}
+
+	return nil
+}
+
+// handleTest runs every reqfile under root. Only the outcome of each reqfile is
+// printed unless it did not pass, in which case its failed assertions and response
+// are printed as well.
+func (a *App) handleTest(root string, opts sendOptions) error {
+	files, err := reql.FindReqfiles(root)
+	if err != nil {
+		return fmt.Errorf("could not retrieve files: %v", err)
+	} else if len(files) == 0 {
+		return fmt.Errorf("no reqfiles found in %s", root)
+	}
+
+	plan, err := reql.NewPlan(files, a.config.Aliases)
+	if err != nil {
+		return fmt.Errorf("could not order request(s): %v", err)
+	}
+
+	runner, err := a.newRunner(opts.parallel)
+	if err != nil {
+		return err
+	}
+
+	summary := make(runSummary)
+	var results []reql.Result
+	runner.Run(plan, func(result reql.Result) {
+		summary[result.Status()]++
+		results = append(results, result)
+		a.printTestResult(result)
+	})
+
+	a.writeReports(opts, results)
+
+	fmt.Fprintf(a.writer, "\n%s\n", summary)
+	if !summary.passed() {
+		return fmt.Errorf("%w: %s", errTestsFailed, summary)
+	}
+
+	return nil
+}
+
+// handleBench benchmarks every reqfile matching glob in turn. Each reqfile is parsed
+// once and the resulting request is sent repeatedly through the app's client.
+func (a *App) handleBench(glob string, opts reql.BenchOptions) error {
 	files, err := a.getFiles(glob)
 	if err != nil {
 		return fmt.Errorf("could not retrieve files: %v", err)
 	}
 
-	err = a.sendRequests(files)
+	env, err := a.loadEnv()
 	if err != nil {
-		return fmt.Errorf("could not send request(s): %v", err)
+		return err
+	}
+
+	for _, file := range files {
+		refs, err := reql.ExpandRequestRef(file)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range refs {
+			reqfile, err := reql.ParseReqfile(ref, env, a.vars)
+			if err != nil {
+				return err
+			}
+
+			a.logger.Info("Benchmarking %s...\n", ref)
+			report := reql.Bench(a.client, reqfile.Request, opts)
+			a.printBenchReport(ref, report)
+		}
 	}
 
 	return nil
 }
 
+// handleList prints every reqfile under the root directory as a tree. Reqfiles that
+// have aliases are annotated with them, and named requests are listed under their
+// reqfile.
 func (a *App) handleList() error {
-	glob := fmt.Sprintf("%s/*.hcl", strings.TrimRight(a.config.Root, "/"))
-	files, err := filepath.Glob(glob)
+	root := a.config.Root
+	if root == "" {
+		root = "."
+	}
+
+	files, err := reql.FindReqfiles(root)
 	if err != nil {
 		return err
 	}
 
-	aliasLookup := make(map[string]string)
-	for k, v := range a.config.Aliases {
-		aliasLookup[v] = k
+	aliasLookup := make(map[string][]string)
+	for alias, path := range a.config.Aliases {
+		if abs, err := filepath.Abs(path); err == nil {
+			aliasLookup[abs] = append(aliasLookup[abs], alias)
+		}
 	}
 
-	for i, file := range files {
-		if alias, ok := aliasLookup[file]; ok {
-			fmt.Fprintf(a.writer, "%d: %s -> %s\n", i+1, alias, file)
-		} else {
-			fmt.Fprintf(a.writer, "%d: %s\n", i+1, file)
+	tree := &treeNode{label: filepath.Clean(root) + string(filepath.Separator)}
+	for _, file := range files {
+		rel, err := filepath.Rel(root, file)
+		if err != nil {
+			return err
+		}
+
+		node := tree
+		dirs := strings.Split(filepath.ToSlash(filepath.Dir(rel)), "/")
+		for _, dir := range dirs {
+			if dir != "." {
+				node = node.child(dir + "/")
+			}
+		}
+
+		label := filepath.Base(file)
+		if abs, err := filepath.Abs(file); err == nil && len(aliasLookup[abs]) > 0 {
+			aliases := aliasLookup[abs]
+			sort.Strings(aliases)
+			label = fmt.Sprintf("%s (%s)", label, strings.Join(aliases, ", "))
+		}
+		node = node.child(label)
+
+		names, err := reql.RequestNames(file)
+		if err != nil {
+			a.logger.Warn("%v", err)
+		}
+		for _, name := range names {
+			node.child(reql.RequestRefSep + name)
 		}
 	}
 
+	tree.render(a.writer)
+
 	return nil
 }
 
@@ -355,13 +750,12 @@ func (a *App) handleNew() error {
 		return err
 	}
 
-	client := reql.NewClient()
-	_, res, err := client.Do(reql.Request{Method: method, URL: url})
+	exchange, err := a.client.Do(reql.Request{Method: method, URL: url})
 	if err != nil {
 		return err
 	}
 
-	err = a.printResponse(res)
+	err = a.printResponse(exchange)
 	if err != nil {
 		return err
 	}
@@ -380,62 +774,317 @@ func (a *App) getInput(prompt string) (string, error) {
 	return strings.Trim(text, " \n"), nil
 }
 
-func (a *App) getFiles(path string) ([]string, error) {
-	var files []string
-	var err error
+// getFiles resolves an alias or glob to reqfile paths. Either may be followed by
+// #name to select a named request in each reqfile. An error is returned if nothing
+// matches so that a mistyped pattern is not mistaken for a passing run.
+func (a *App) getFiles(ref string) ([]string, error) {
+	path, name := reql.SplitRequestRef(ref)
 
 	if alias, ok := a.config.Aliases[path]; ok {
-		files = append(files, alias)
-	} else {
-		files, err = filepath.Glob(path)
-		if err != nil {
-			return nil, err
-		}
+		return []string{reql.JoinRequestRef(alias, name)}, nil
+	}
+
+	files, err := reql.Glob(path)
+	if err != nil {
+		return nil, err
+	} else if len(files) == 0 {
+		return nil, fmt.Errorf("no reqfiles match %q", ref)
+	}
+
+	for i := range files {
+		files[i] = reql.JoinRequestRef(files[i], name)
 	}
 
 	return files, nil
 }
 
-func (a *App) sendRequests(files []string) error {
-	for _, file := range files {
-		a.logger.Info("Running %s...\n", file)
-		reqfile, err := reql.ParseReqfile(file, a.config.Environments[a.env])
-		if err != nil {
-			return err
+// errSendFailed and errTestsFailed are returned when at least one reqfile did not
+// pass. The CLI surfaces them as a non-zero exit status.
+var (
+	errSendFailed    = errors.New("send failed")
+	errTestsFailed   = errors.New("tests failed")
+	errConfigInvalid = errors.New("config is invalid")
+)
+
+// runSummary counts the results of a run by status.
+type runSummary map[reql.Status]int
+
+func (s runSummary) passed() bool {
+	return s[reql.StatusFailed]+s[reql.StatusErrored]+s[reql.StatusSkipped] == 0
+}
+
+func (s runSummary) String() string {
+	return fmt.Sprintf(
+		"%d passed, %d failed, %d errored, %d skipped",
+		s[reql.StatusPassed],
+		s[reql.StatusFailed],
+		s[reql.StatusErrored],
+		s[reql.StatusSkipped],
+	)
+}
+
+// sendRequests runs the steps of the plan with up to opts.parallel requests in flight
+// and prints each result in plan order in the selected output format.
+func (a *App) sendRequests(plan *reql.Plan, opts sendOptions) (runSummary, error) {
+	runner, err := a.newRunner(opts.parallel)
+	if err != nil {
+		return nil, err
+	}
+
+	summary := make(runSummary)
+	var results []jsonResult
+	var reported []reql.Result
+	runner.Run(plan, func(result reql.Result) {
+		summary[result.Status()]++
+		if len(opts.reports) > 0 {
+			reported = append(reported, result)
 		}
 
-		client := reql.NewClient()
-		_, response, err := client.Do(reqfile.Request)
-		if err != nil {
-			return err
+		switch opts.output {
+		case outputJSON:
+			results = append(results, newJSONResult(result, a.mask))
+		case outputJSONL:
+			if err := writeJSONL(a.writer, newJSONResult(result, a.mask)); err != nil {
+				a.logger.Error(err.Error())
+			}
+		default:
+			a.printResult(result)
 		}
+	})
 
-		err = a.printResponse(response)
+	if opts.output == outputJSON {
+		if err := writeJSON(a.writer, results); err != nil {
+			a.logger.Error(err.Error())
+		}
+	}
+
+	a.writeReports(opts, reported)
+
+	return summary, nil
+}
+
+// newRunner returns a runner that sends requests in the current env.
+func (a *App) newRunner(parallel int) (*reql.Runner, error) {
+	env, err := a.loadEnv()
+	if err != nil {
+		return nil, err
+	}
+
+	return &reql.Runner{
+		Client:   a.client,
+		Env:      env,
+		Vars:     a.vars,
+		Logger:   a.logger,
+		Parallel: parallel,
+	}, nil
+}
+
+func (a *App) printResult(result reql.Result) {
+	if result.Skipped {
+		a.logger.Warn("Skipped %s: %v", result.Path, result.Err)
+		return
+	}
+
+	if result.Exchange != nil {
+		err := a.printResponse(result.Exchange)
 		if err != nil {
-			return err
+			a.logger.Error(err.Error())
 		}
+
+		a.printAssertions(result.Assertions)
 	}
 
-	return nil
+	if result.Err != nil {
+		a.logger.Error("%s: %v", result.Path, result.Err)
+	}
 }
 
-func (a *App) printResponse(response *http.Response) error {
-	a.logger.Info("Got response...\n\n")
-	fmt.Fprintf(a.writer, "%s %s\n", response.Proto, response.Status)
+// printTestResult writes a single line with the outcome of a reqfile. Failed
+// assertions and the response are only written when the reqfile did not pass.
+func (a *App) printTestResult(result reql.Result) {
+	status := result.Status()
+
+	label := map[reql.Status]string{
+		reql.StatusPassed:  paint(a.color, ansiGreen, "PASS "),
+		reql.StatusFailed:  paint(a.color, ansiRed, "FAIL "),
+		reql.StatusErrored: paint(a.color, ansiRed, "ERROR"),
+		reql.StatusSkipped: paint(a.color, ansiYellow, "SKIP "),
+	}[status]
+
+	line := fmt.Sprintf("%s %s", label, result.Path)
+	if result.Exchange != nil {
+		line += fmt.Sprintf(" (%s)", result.Exchange.Timing.Total.Round(time.Microsecond))
+	}
+	if result.Err != nil {
+		line += fmt.Sprintf(": %v", result.Err)
+	}
+	fmt.Fprintln(a.writer, line)
+
+	if status == reql.StatusPassed || status == reql.StatusSkipped {
+		return
+	}
+
+	for _, assertion := range result.Assertions {
+		if assertion.Err != nil {
+			fmt.Fprintf(a.writer, "      %s: %v\n", assertion.Name, assertion.Err)
+		}
+	}
+
+	if result.Exchange != nil {
+		fmt.Fprint(a.writer, "\n")
+		if err := a.printResponse(result.Exchange); err != nil {
+			a.logger.Error(err.Error())
+		}
+	}
+}
+
+// printAssertions writes a pass/fail line for every assertion.
+func (a *App) printAssertions(results []reql.AssertionResult) {
+	if len(results) == 0 {
+		return
+	}
+
+	failed := 0
+	fmt.Fprint(a.writer, "Assertions:\n")
+	for _, result := range results {
+		if result.Err != nil {
+			failed++
+			fmt.Fprintf(a.writer, "  FAIL  %s: %v\n", result.Name, result.Err)
+		} else {
+			fmt.Fprintf(a.writer, "  PASS  %s\n", result.Name)
+		}
+	}
+	fmt.Fprintf(a.writer, "%d passed, %d failed\n\n", len(results)-failed, failed)
+}
+
+func (a *App) printResponse(exchange *reql.Exchange) error {
+	response := exchange.Response
+	a.logger.Info("Got response over %s in %s...\n", response.Proto, exchange.Timing.Total.Round(time.Microsecond))
+	a.logger.Info("Timing: %s\n\n", exchange.Timing)
+	status := a.mask(response.Proto + " " + response.Status)
+	fmt.Fprintf(a.writer, "%s\n", paint(a.color, statusColor(response.StatusCode), status))
 	for k := range response.Header {
 		for _, v := range response.Header.Values(k) {
-			fmt.Fprintf(a.writer, "%s: %s\n", k, v)
+			fmt.Fprintf(a.writer, "%s: %s\n", paint(a.color, ansiCyan, k), a.mask(v))
 		}
 	}
 	fmt.Fprint(a.writer, "\n")
 
-	buf, err := io.ReadAll(response.Body)
+	body := exchange.Body
+	if !a.raw {
+		body = formatBody(response.Header.Get("Content-Type"), body, a.color)
+	}
+
+	if len(body) > 0 {
+		fmt.Fprintf(a.writer, "%s\n", a.mask(string(body)))
+	}
+
+	return nil
+}
+
+func (a *App) printBenchReport(file string, report *reql.BenchReport) {
+	fmt.Fprintf(a.writer, "%s\n", file)
+	fmt.Fprintf(a.writer, "  Requests:    %d (%d errors)\n", report.Requests, report.ErrorCount())
+	fmt.Fprintf(a.writer, "  Elapsed:     %s\n", report.Elapsed.Round(time.Millisecond))
+	fmt.Fprintf(a.writer, "  Throughput:  %.2f req/s\n", report.Throughput())
+
+	codes := make([]int, 0, len(report.StatusCodes))
+	for code := range report.StatusCodes {
+		codes = append(codes, code)
+	}
+	sort.Ints(codes)
+
+	fmt.Fprint(a.writer, "  Status codes:\n")
+	for _, code := range codes {
+		fmt.Fprintf(a.writer, "    %d: %d\n", code, report.StatusCodes[code])
+	}
+
+	if len(report.Errors) > 0 {
+		messages := make([]string, 0, len(report.Errors))
+		for message := range report.Errors {
+			messages = append(messages, message)
+		}
+		sort.Strings(messages)
+
+		fmt.Fprint(a.writer, "  Errors:\n")
+		for _, message := range messages {
+			fmt.Fprintf(a.writer, "    %d x %s\n", report.Errors[message], message)
+		}
+	}
+
+	fmt.Fprint(a.writer, "  Latency:\n")
+	fmt.Fprintf(a.writer, "    p50: %s\n", report.Percentile(50))
+	fmt.Fprintf(a.writer, "    p90: %s\n", report.Percentile(90))
+	fmt.Fprintf(a.writer, "    p99: %s\n", report.Percentile(99))
+	fmt.Fprintf(a.writer, "    max: %s\n\n", report.Max())
+}
+
+// printVars writes the captured values in name order with the secrets of the current
+// env masked. Strings are written as is and other values as JSON.
+func (a *App) printVars() error {
+	for _, name := range a.vars.Names() {
+		v, _ := a.vars.Get(name)
+		if v.Type() == cty.String && !v.IsNull() {
+			fmt.Fprintf(a.writer, "%s = %s\n", name, a.mask(v.AsString()))
+			continue
+		}
+
+		b, err := ctyjson.Marshal(v, v.Type())
+		if err != nil {
+			return err
+		}
+		fmt.Fprintf(a.writer, "%s = %s\n", name, a.mask(string(b)))
+	}
+
+	return nil
+}
+
+// printEnv writes the values of the current env in key order. Strings are written
+// as is and other values as JSON. If the env extends other envs, each value is
+// followed by the name of the env it came from.
+func (a *App) printEnv() error {
+	if _, err := a.loadEnv(); err != nil {
+		return err
+	}
+
+	layers, err := a.config.EnvLayers(a.env)
 	if err != nil {
 		return err
 	}
+	env, sources, err := a.config.ResolveEnv(a.env)
+	if err != nil {
+		return err
+	}
+
+	if len(layers) > 1 {
+		fmt.Fprintf(a.writer, "# %s\n", strings.Join(layers, " -> "))
+	}
 
-	if len(buf) > 0 {
-		fmt.Fprintf(a.writer, "%s\n", buf)
+	keys := make([]string, 0, len(env))
+	for k := range env {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	for _, k := range keys {
+		var value string
+		if s, ok := env[k].(string); ok && strings.HasPrefix(s, reql.SecretPrefix) {
+			value = fmt.Sprintf("%s (%s)", reql.SecretMask, s)
+		} else if ok {
+			value = a.mask(s)
+		} else {
+			b, err := json.Marshal(env[k])
+			if err != nil {
+				return err
+			}
+			value = a.mask(string(b))
+		}
+
+		if len(layers) > 1 {
+			fmt.Fprintf(a.writer, "%s = %s  # %s\n", k, value, sources[k])
+		} else {
+			fmt.Fprintf(a.writer, "%s = %s\n", k, value)
+		}
 	}
 
 	return nil
@@ -445,12 +1094,16 @@ func (a *App) printHelp() {
 	fmt.Fprint(a.writer, "Available commands:\n")
 	fmt.Fprint(a.writer, "  h, help              Display this help message.\n")
 	fmt.Fprint(a.writer, "  list                 List all available requests including aliases.\n")
-	fmt.Fprint(a.writer, "  send {alias|glob}    Send a request.\n")
+	fmt.Fprint(a.writer, "  send [-p N] {alias|glob}\n")
+	fmt.Fprint(a.writer, "                       Send a request. With -p, send up to N independent requests at once.\n")
 	fmt.Fprint(a.writer, "  new    				 Interactively define a new request.\n")
 	fmt.Fprint(a.writer, "  env                  Display all values in the current env.\n")
 	fmt.Fprint(a.writer, "  env-select {env}     Change the current env.\n")
 	fmt.Fprint(a.writer, "  env-new {env}        Create a new env and switch to it.\n")
 	fmt.Fprint(a.writer, "  env-set {key} {val}  Set a value in the current env.\n")
 	fmt.Fprint(a.writer, "  env-delete {key}     Delete a value from the current env.\n")
+	fmt.Fprint(a.writer, "  vars                 Display all values captured in this session.\n")
+	fmt.Fprint(a.writer, "  vars-clear           Delete all captured values.\n")
+	fmt.Fprint(a.writer, "  save                 Save env changes to the config file.\n")
 	fmt.Fprint(a.writer, "  q, quit, exit        Exit the REPL.\n")
 }
@@ -0,0 +1,110 @@
+package cli
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/mattmeyers/reql"
+	"github.com/zclconf/go-cty/cty"
+)
+
+// runApp runs the CLI with args against a project containing files, which map
+// paths relative to the project directory to their contents. It returns the error
+// that would make the process exit with a non-zero status.
+func runApp(t *testing.T, files map[string]string, args ...string) error {
+	t.Helper()
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	dir := t.TempDir()
+	for path, src := range files {
+		path = filepath.Join(dir, path)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	wd, err := os.Getwd()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Chdir(dir); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { os.Chdir(wd) })
+
+	a := New(append([]string{"req"}, args...))
+	a.writer = io.Discard
+
+	return a.Run()
+}
+
+func TestApp_Send(t *testing.T) {
+	const request = "request {\n  method = \"GET\"\n  url = \"http://127.0.0.1:1\"\n}\n"
+
+	tests := []struct {
+		name    string
+		files   map[string]string
+		args    []string
+		wantErr string
+	}{
+		{
+			name: "Dependency cycle",
+			files: map[string]string{
+				".reqrc": "",
+				"a.hcl":  "depends_on = [\"b.hcl\"]\n" + request,
+				"b.hcl":  "depends_on = [\"a.hcl\"]\n" + request,
+			},
+			args:    []string{"send", "*.hcl"},
+			wantErr: "cycle",
+		},
+		{
+			name: "No matching reqfiles",
+			files: map[string]string{
+				".reqrc": "",
+				"a.hcl":  request,
+			},
+			args:    []string{"send", "requests/*.hcl"},
+			wantErr: `no reqfiles match "requests/*.hcl"`,
+		},
+		{
+			name: "Unknown dependency",
+			files: map[string]string{
+				".reqrc": "",
+				"a.hcl":  "depends_on = [\"missing.hcl\"]\n" + request,
+			},
+			args:    []string{"send", "a.hcl"},
+			wantErr: "missing.hcl",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := runApp(t, tt.files, tt.args...)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("App.Run() error = %v, want error containing %q", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestApp_printVars(t *testing.T) {
+	vars := reql.NewVars()
+	vars.Set("token", cty.StringVal("Bearer s3cr3t"))
+	vars.Set("session", cty.ObjectVal(map[string]cty.Value{"token": cty.StringVal("s3cr3t")}))
+
+	var b strings.Builder
+	a := &App{writer: &b, vars: vars, secrets: []string{"s3cr3t"}}
+	if err := a.printVars(); err != nil {
+		t.Fatalf("App.printVars() error = %v", err)
+	}
+
+	want := "session = {\"token\":\"********\"}\ntoken = Bearer ********\n"
+	if got := b.String(); got != want {
+		t.Errorf("App.printVars() = %q, want %q", got, want)
+	}
+}
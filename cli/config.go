@@ -0,0 +1,53 @@
+package cli
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/urfave/cli/v2"
+)
+
+func (a *App) configCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "config",
+		Usage: "Inspect the configuration",
+		Subcommands: []*cli.Command{
+			{
+				Name:   "show",
+				Usage:  "Print the effective config merged from the user and project config files",
+				Action: a.handleConfigShow,
+			},
+			{
+				Name:   "validate",
+				Usage:  "Check the config files for unknown keys, undefined envs, and missing files",
+				Action: a.handleConfigValidate,
+			},
+		},
+	}
+}
+
+func (a *App) handleConfigShow(c *cli.Context) error {
+	return a.config.WriteEffective(a.writer)
+}
+
+// handleConfigValidate prints every problem with the config and fails if there is
+// any.
+func (a *App) handleConfigValidate(c *cli.Context) error {
+	errs := a.config.Validate()
+	for _, err := range errs {
+		fmt.Fprintln(a.writer, err)
+	}
+
+	if len(errs) > 0 {
+		return errConfigInvalid
+	}
+
+	files := a.config.Files()
+	if len(files) == 0 {
+		fmt.Fprintln(a.writer, "No config files found")
+		return nil
+	}
+	fmt.Fprintf(a.writer, "%s: ok\n", strings.Join(files, ", "))
+
+	return nil
+}
@@ -0,0 +1,240 @@
+package cli
+
+import (
+	"bytes"
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"mime"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+)
+
+// ANSI escape sequences used to colorize output written to a terminal.
+const (
+	ansiReset  = "\x1b[0m"
+	ansiRed    = "\x1b[31m"
+	ansiGreen  = "\x1b[32m"
+	ansiYellow = "\x1b[33m"
+	ansiBlue   = "\x1b[34m"
+	ansiCyan   = "\x1b[36m"
+)
+
+// isTerminal reports whether w is a character device such as an interactive
+// terminal. Colors are never used when the NO_COLOR environment variable is set.
+func isTerminal(w io.Writer) bool {
+	if _, ok := os.LookupEnv("NO_COLOR"); ok {
+		return false
+	}
+
+	f, ok := w.(*os.File)
+	if !ok {
+		return false
+	}
+
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+
+	return info.Mode()&os.ModeCharDevice != 0
+}
+
+// paint wraps s in the given ANSI color when color is true.
+func paint(color bool, code, s string) string {
+	if !color {
+		return s
+	}
+
+	return code + s + ansiReset
+}
+
+// statusColor picks the color of a status line by status code class.
+func statusColor(code int) string {
+	switch {
+	case code >= 500:
+		return ansiRed
+	case code >= 400:
+		return ansiYellow
+	case code >= 300:
+		return ansiCyan
+	default:
+		return ansiGreen
+	}
+}
+
+// formatBody pretty prints body according to contentType. JSON and XML bodies are
+// indented and form encoded bodies are listed one key/value pair per line. When
+// color is true, JSON bodies are also syntax highlighted. Bodies of any other type,
+// or that fail to parse as their declared type, are returned unchanged.
+func formatBody(contentType string, body []byte, color bool) []byte {
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return body
+	}
+
+	var formatted []byte
+	switch {
+	case mediaType == "application/json" || strings.HasSuffix(mediaType, "+json"):
+		formatted, err = formatJSON(body, color)
+	case mediaType == "application/xml" || mediaType == "text/xml" || strings.HasSuffix(mediaType, "+xml"):
+		formatted, err = formatXML(body)
+	case mediaType == "application/x-www-form-urlencoded":
+		formatted, err = formatForm(body, color)
+	default:
+		return body
+	}
+
+	if err != nil {
+		return body
+	}
+
+	return formatted
+}
+
+func formatJSON(body []byte, color bool) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, body, "", "  "); err != nil {
+		return nil, err
+	}
+
+	if !color {
+		return buf.Bytes(), nil
+	}
+
+	return highlightJSON(buf.Bytes()), nil
+}
+
+// highlightJSON colorizes the keys, strings, and scalar values of a valid JSON
+// document.
+func highlightJSON(src []byte) []byte {
+	var out bytes.Buffer
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case c == '"':
+			end := i + 1
+			for end < len(src) && src[end] != '"' {
+				if src[end] == '\\' {
+					end++
+				}
+				end++
+			}
+			end++
+
+			code := ansiGreen
+			if isJSONKey(src[end:]) {
+				code = ansiBlue
+			}
+			out.WriteString(paint(true, code, string(src[i:end])))
+			i = end
+		case c == '-' || (c >= '0' && c <= '9') || c == 't' || c == 'f' || c == 'n':
+			end := i
+			for end < len(src) && !strings.ContainsRune(",]} \n", rune(src[end])) {
+				end++
+			}
+			out.WriteString(paint(true, ansiYellow, string(src[i:end])))
+			i = end
+		default:
+			out.WriteByte(c)
+			i++
+		}
+	}
+
+	return out.Bytes()
+}
+
+// isJSONKey reports whether the string that ended just before rest is an object key.
+func isJSONKey(rest []byte) bool {
+	rest = bytes.TrimLeft(rest, " ")
+	return len(rest) > 0 && rest[0] == ':'
+}
+
+// formatXML re-encodes body with indentation. Namespace prefixes are kept as written.
+func formatXML(body []byte) ([]byte, error) {
+	var buf bytes.Buffer
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	enc := xml.NewEncoder(&buf)
+	enc.Indent("", "  ")
+
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		switch t := tok.(type) {
+		case xml.CharData:
+			if len(bytes.TrimSpace(t)) == 0 {
+				continue
+			}
+		case xml.StartElement:
+			t.Name = rawXMLName(t.Name)
+			for i := range t.Attr {
+				t.Attr[i].Name = rawXMLName(t.Attr[i].Name)
+			}
+			tok = t
+		case xml.EndElement:
+			t.Name = rawXMLName(t.Name)
+			tok = t
+		}
+
+		if err := enc.EncodeToken(tok); err != nil {
+			return nil, err
+		}
+
+		// The encoder does not indent after a declaration, so break the line here.
+		if _, ok := tok.(xml.ProcInst); ok {
+			if err := enc.Flush(); err != nil {
+				return nil, err
+			}
+			buf.WriteByte('\n')
+		}
+	}
+
+	if err := enc.Flush(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// rawXMLName folds a namespace prefix into the local name so that the encoder writes
+// it back verbatim instead of declaring a new namespace.
+func rawXMLName(name xml.Name) xml.Name {
+	if name.Space == "" {
+		return name
+	}
+
+	return xml.Name{Local: name.Space + ":" + name.Local}
+}
+
+func formatForm(body []byte, color bool) ([]byte, error) {
+	values, err := url.ParseQuery(string(body))
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(values))
+	for k := range values {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf bytes.Buffer
+	for _, k := range keys {
+		for _, v := range values[k] {
+			if buf.Len() > 0 {
+				buf.WriteByte('\n')
+			}
+			fmt.Fprintf(&buf, "%s = %s", paint(color, ansiBlue, k), v)
+		}
+	}
+
+	return buf.Bytes(), nil
+}
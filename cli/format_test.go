@@ -0,0 +1,79 @@
+package cli
+
+import (
+	"testing"
+)
+
+func Test_formatBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		contentType string
+		body        string
+		color       bool
+		want        string
+	}{
+		{
+			name:        "JSON",
+			contentType: "application/json; charset=utf-8",
+			body:        `{"a":[1,true],"b":"x"}`,
+			want:        "{\n  \"a\": [\n    1,\n    true\n  ],\n  \"b\": \"x\"\n}",
+		},
+		{
+			name:        "JSON suffix",
+			contentType: "application/problem+json",
+			body:        `{"a":null}`,
+			want:        "{\n  \"a\": null\n}",
+		},
+		{
+			name:        "Highlighted JSON",
+			contentType: "application/json",
+			body:        `{"a":"b:\"c\"","n":-1.5}`,
+			color:       true,
+			want: "{\n  " + ansiBlue + `"a"` + ansiReset + ": " + ansiGreen + `"b:\"c\""` + ansiReset + ",\n  " +
+				ansiBlue + `"n"` + ansiReset + ": " + ansiYellow + "-1.5" + ansiReset + "\n}",
+		},
+		{
+			name:        "Invalid JSON",
+			contentType: "application/json",
+			body:        `{"a":`,
+			want:        `{"a":`,
+		},
+		{
+			name:        "XML",
+			contentType: "text/xml",
+			body:        `<?xml version="1.0"?><soap:Envelope xmlns:soap="urn:x"><soap:Body a="1"><v>hi</v></soap:Body></soap:Envelope>`,
+			want:        "<?xml version=\"1.0\"?>\n<soap:Envelope xmlns:soap=\"urn:x\">\n  <soap:Body a=\"1\">\n    <v>hi</v>\n  </soap:Body>\n</soap:Envelope>",
+		},
+		{
+			name:        "Invalid XML",
+			contentType: "application/xml",
+			body:        `<a><b></a>`,
+			want:        `<a><b></a>`,
+		},
+		{
+			name:        "Form",
+			contentType: "application/x-www-form-urlencoded",
+			body:        "b=2&a=1&a=x+y",
+			want:        "a = 1\na = x y\nb = 2",
+		},
+		{
+			name:        "Plain text",
+			contentType: "text/plain",
+			body:        `{"a":1}`,
+			want:        `{"a":1}`,
+		},
+		{
+			name:        "No content type",
+			contentType: "",
+			body:        `{"a":1}`,
+			want:        `{"a":1}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := string(formatBody(tt.contentType, []byte(tt.body), tt.color)); got != tt.want {
+				t.Errorf("formatBody() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,150 @@
+package cli
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"strings"
+
+	"github.com/mattmeyers/reql"
+)
+
+// The output formats supported by the send command.
+const (
+	outputText  = "text"
+	outputJSON  = "json"
+	outputJSONL = "jsonl"
+)
+
+// jsonResult is the machine readable form of a reql.Result. Request, Response, and
+// Timing are omitted when the request was never sent or no response was received.
+type jsonResult struct {
+	Path       string          `json:"path"`
+	Status     string          `json:"status"`
+	Error      string          `json:"error,omitempty"`
+	Request    *jsonRequest    `json:"request,omitempty"`
+	Response   *jsonResponse   `json:"response,omitempty"`
+	Timing     *jsonTiming     `json:"timing,omitempty"`
+	Assertions []jsonAssertion `json:"assertions"`
+}
+
+type jsonRequest struct {
+	Method  string      `json:"method"`
+	URL     string      `json:"url"`
+	Headers http.Header `json:"headers"`
+	Body    string      `json:"body"`
+}
+
+// jsonResponse holds the response body as a string. When the body is valid JSON, it
+// is also embedded as is under JSON so that it can be queried directly.
+type jsonResponse struct {
+	Proto   string          `json:"proto"`
+	Code    int             `json:"code"`
+	Status  string          `json:"status"`
+	Headers http.Header     `json:"headers"`
+	Body    string          `json:"body"`
+	JSON    json.RawMessage `json:"json,omitempty"`
+}
+
+type jsonTiming struct {
+	DNS             float64 `json:"dns_ms"`
+	Connect         float64 `json:"connect_ms"`
+	TLSHandshake    float64 `json:"tls_ms"`
+	TimeToFirstByte float64 `json:"ttfb_ms"`
+	Total           float64 `json:"total_ms"`
+}
+
+type jsonAssertion struct {
+	Name   string `json:"name"`
+	Passed bool   `json:"passed"`
+	Error  string `json:"error,omitempty"`
+}
+
+// newJSONResult converts a result to its machine readable form. Every string that
+// may hold a secret value is passed through mask.
+func newJSONResult(result reql.Result, mask func(string) string) jsonResult {
+	out := jsonResult{
+		Path:       result.Path,
+		Status:     strings.ToLower(result.Status().String()),
+		Assertions: make([]jsonAssertion, len(result.Assertions)),
+	}
+
+	if result.Err != nil {
+		out.Error = mask(result.Err.Error())
+	}
+
+	for i, a := range result.Assertions {
+		out.Assertions[i] = jsonAssertion{Name: a.Name, Passed: a.Err == nil}
+		if a.Err != nil {
+			out.Assertions[i].Error = mask(a.Err.Error())
+		}
+	}
+
+	if ex := result.Exchange; ex != nil && ex.Response != nil {
+		if ex.HTTPRequest != nil {
+			out.Request = &jsonRequest{
+				Method:  ex.HTTPRequest.Method,
+				URL:     mask(ex.HTTPRequest.URL.String()),
+				Headers: maskHeader(ex.HTTPRequest.Header, mask),
+				Body:    mask(ex.Request.Body),
+			}
+		}
+
+		body := mask(string(ex.Body))
+		out.Response = &jsonResponse{
+			Proto:   ex.Response.Proto,
+			Code:    ex.Response.StatusCode,
+			Status:  mask(ex.Response.Status),
+			Headers: maskHeader(ex.Response.Header, mask),
+			Body:    body,
+		}
+		if json.Valid([]byte(body)) {
+			out.Response.JSON = json.RawMessage(body)
+		}
+
+		out.Timing = &jsonTiming{
+			DNS:             reql.Millis(ex.Timing.DNS),
+			Connect:         reql.Millis(ex.Timing.Connect),
+			TLSHandshake:    reql.Millis(ex.Timing.TLSHandshake),
+			TimeToFirstByte: reql.Millis(ex.Timing.TimeToFirstByte),
+			Total:           reql.Millis(ex.Timing.Total),
+		}
+	}
+
+	return out
+}
+
+// writeJSON writes results as a single indented JSON array. No results are written
+// as an empty array rather than null.
+func writeJSON(w io.Writer, results []jsonResult) error {
+	if results == nil {
+		results = []jsonResult{}
+	}
+
+	enc := json.NewEncoder(w)
+	enc.SetIndent("", "  ")
+
+	return enc.Encode(results)
+}
+
+// writeJSONL writes result as a single line of JSON.
+func writeJSONL(w io.Writer, result jsonResult) error {
+	return json.NewEncoder(w).Encode(result)
+}
+
+// maskHeader returns a copy of h with every value passed through mask.
+func maskHeader(h http.Header, mask func(string) string) http.Header {
+	if h == nil {
+		return nil
+	}
+
+	masked := make(http.Header, len(h))
+	for k, values := range h {
+		masked[k] = make([]string, len(values))
+		for i, v := range values {
+			masked[k][i] = mask(v)
+		}
+	}
+
+	return masked
+}
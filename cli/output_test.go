@@ -0,0 +1,208 @@
+package cli
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/reql"
+)
+
+func Test_newJSONResult_MasksSecrets(t *testing.T) {
+	const secret = "s3cr3t"
+
+	u, _ := url.Parse("http://localhost/login?token=" + secret)
+	ex := &reql.Exchange{
+		Request: reql.Request{Method: "POST", URL: u.String(), Body: `{"password":"` + secret + `"}`},
+		HTTPRequest: &http.Request{
+			Method: "POST",
+			URL:    u,
+			Header: http.Header{"Authorization": {"Bearer " + secret}},
+		},
+		Response: &http.Response{
+			Proto:      "HTTP/1.1",
+			Status:     "200 OK",
+			StatusCode: 200,
+			Header:     http.Header{"Set-Cookie": {"session=" + secret}},
+		},
+		Body: []byte(`{"echo":"` + secret + `"}`),
+	}
+	result := reql.Result{
+		Path:       "login.hcl",
+		Exchange:   ex,
+		Assertions: []reql.AssertionResult{{Name: "Body #1", Err: errors.New("got " + secret)}},
+	}
+
+	mask := func(s string) string { return reql.MaskSecrets(s, []string{secret}) }
+	data, err := json.Marshal(newJSONResult(result, mask))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Errorf("newJSONResult() leaked the secret: %s", data)
+	}
+	if !strings.Contains(string(data), reql.SecretMask) {
+		t.Errorf("newJSONResult() = %s, want the secret replaced by %s", data, reql.SecretMask)
+	}
+}
+
+func Test_newJSONResult(t *testing.T) {
+	u, _ := url.Parse("http://localhost/ping")
+	ex := &reql.Exchange{
+		Request:     reql.Request{Method: "GET", URL: u.String()},
+		HTTPRequest: &http.Request{Method: "GET", URL: u, Header: http.Header{}},
+		Response: &http.Response{
+			Proto:      "HTTP/1.1",
+			Status:     "200 OK",
+			StatusCode: 200,
+			Header:     http.Header{"Content-Type": {"application/json"}},
+		},
+		Body:   []byte(`{"ok":true}`),
+		Timing: reql.Timing{Total: 1500 * time.Microsecond},
+	}
+
+	tests := []struct {
+		name           string
+		result         reql.Result
+		wantStatus     string
+		wantKeys       []string
+		wantMissing    []string
+		wantAssertions []jsonAssertion
+	}{
+		{
+			name: "Passed",
+			result: reql.Result{
+				Path:       "ping.hcl",
+				Exchange:   ex,
+				Assertions: []reql.AssertionResult{{Name: "Status"}},
+			},
+			wantStatus:     "passed",
+			wantKeys:       []string{"path", "status", "request", "response", "timing", "assertions"},
+			wantMissing:    []string{"error"},
+			wantAssertions: []jsonAssertion{{Name: "Status", Passed: true}},
+		},
+		{
+			name: "Failed assertion",
+			result: reql.Result{
+				Path:     "ping.hcl",
+				Exchange: ex,
+				Assertions: []reql.AssertionResult{
+					{Name: "Status"},
+					{Name: "Body", Err: errors.New(`expected res.body == "pong"`)},
+				},
+			},
+			wantStatus:  "failed",
+			wantKeys:    []string{"path", "status", "request", "response", "timing", "assertions"},
+			wantMissing: []string{"error"},
+			wantAssertions: []jsonAssertion{
+				{Name: "Status", Passed: true},
+				{Name: "Body", Error: `expected res.body == "pong"`},
+			},
+		},
+		{
+			name: "Never sent",
+			result: reql.Result{
+				Path: "broken.hcl",
+				Err:  errors.New("connection refused"),
+			},
+			wantStatus:     "errored",
+			wantKeys:       []string{"path", "status", "error", "assertions"},
+			wantMissing:    []string{"request", "response", "timing"},
+			wantAssertions: []jsonAssertion{},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := newJSONResult(tt.result, noMask)
+			if got.Status != tt.wantStatus {
+				t.Errorf("newJSONResult() Status = %q, want %q", got.Status, tt.wantStatus)
+			}
+			if !reflect.DeepEqual(got.Assertions, tt.wantAssertions) {
+				t.Errorf("newJSONResult() Assertions = %+v, want %+v", got.Assertions, tt.wantAssertions)
+			}
+
+			data, err := json.Marshal(got)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var fields map[string]json.RawMessage
+			if err := json.Unmarshal(data, &fields); err != nil {
+				t.Fatal(err)
+			}
+			for _, key := range tt.wantKeys {
+				if _, ok := fields[key]; !ok {
+					t.Errorf("newJSONResult() = %s, want field %q", data, key)
+				}
+			}
+			for _, key := range tt.wantMissing {
+				if _, ok := fields[key]; ok {
+					t.Errorf("newJSONResult() = %s, want no field %q", data, key)
+				}
+			}
+		})
+	}
+
+	got := newJSONResult(reql.Result{Path: "ping.hcl", Exchange: ex}, noMask)
+	if got.Response == nil || string(got.Response.JSON) != `{"ok":true}` || got.Response.Code != 200 {
+		t.Errorf("newJSONResult() Response = %+v, want the JSON body embedded", got.Response)
+	}
+	if got.Timing == nil || got.Timing.Total != 1.5 {
+		t.Errorf("newJSONResult() Timing = %+v, want total_ms 1.5", got.Timing)
+	}
+}
+
+func Test_writeJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		results []jsonResult
+		want    string
+	}{
+		{
+			name: "No results",
+			want: "[]\n",
+		},
+		{
+			name:    "Indented array",
+			results: []jsonResult{{Path: "ping.hcl", Status: "passed", Assertions: []jsonAssertion{}}},
+			want:    "[\n  {\n    \"path\": \"ping.hcl\",\n    \"status\": \"passed\",\n    \"assertions\": []\n  }\n]\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := writeJSON(&b, tt.results); err != nil {
+				t.Fatalf("writeJSON() error = %v", err)
+			}
+			if got := b.String(); got != tt.want {
+				t.Errorf("writeJSON() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeJSONL(t *testing.T) {
+	var b strings.Builder
+	for _, result := range testResults() {
+		if err := writeJSONL(&b, newJSONResult(result, noMask)); err != nil {
+			t.Fatalf("writeJSONL() error = %v", err)
+		}
+	}
+
+	lines := strings.Split(strings.TrimSuffix(b.String(), "\n"), "\n")
+	if len(lines) != len(testResults()) {
+		t.Fatalf("writeJSONL() wrote %d lines, want %d: %s", len(lines), len(testResults()), b.String())
+	}
+	for i, line := range lines {
+		var got jsonResult
+		if err := json.Unmarshal([]byte(line), &got); err != nil {
+			t.Errorf("writeJSONL() line %d = %s, want a JSON object: %v", i+1, line, err)
+		} else if got.Path != testResults()[i].Path {
+			t.Errorf("writeJSONL() line %d path = %q, want %q", i+1, got.Path, testResults()[i].Path)
+		}
+	}
+}
@@ -0,0 +1,330 @@
+package cli
+
+import (
+	"encoding/xml"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mattmeyers/reql"
+)
+
+// The report formats supported by the send command.
+const (
+	reportJUnit = "junit"
+	reportTAP   = "tap"
+)
+
+// maxSnippetLen is the number of response body bytes included in reports.
+const maxSnippetLen = 1024
+
+// reportSpec selects a report format and where to write it. An empty path or "-"
+// writes the report to stdout after text output, and to stderr otherwise so that JSON
+// output stays valid.
+type reportSpec struct {
+	format string
+	path   string
+}
+
+// parseReportSpec parses a report option of the form FORMAT[=PATH].
+func parseReportSpec(s string) (reportSpec, error) {
+	parts := strings.SplitN(s, "=", 2)
+
+	spec := reportSpec{format: strings.TrimSpace(parts[0])}
+	if len(parts) == 2 {
+		spec.path = strings.TrimSpace(parts[1])
+	}
+	switch spec.format {
+	case reportJUnit, reportTAP:
+	default:
+		return spec, fmt.Errorf("unknown report format %q", spec.format)
+	}
+
+	return spec, nil
+}
+
+// parseReportSpecs parses every report option.
+func parseReportSpecs(options []string) ([]reportSpec, error) {
+	var specs []reportSpec
+	for _, option := range options {
+		spec, err := parseReportSpec(option)
+		if err != nil {
+			return nil, err
+		}
+		specs = append(specs, spec)
+	}
+
+	return specs, nil
+}
+
+// writeReports writes every report of opts, logging the ones that fail.
+func (a *App) writeReports(opts sendOptions, results []reql.Result) {
+	w := a.writer
+	if opts.output != outputText {
+		w = a.errWriter
+	}
+
+	for _, spec := range opts.reports {
+		if err := a.writeReport(w, spec, results); err != nil {
+			a.logger.Error("could not write %s report: %v", spec.format, err)
+		}
+	}
+}
+
+// writeReport writes the report selected by spec to its path, or to w if it has none.
+func (a *App) writeReport(w io.Writer, spec reportSpec, results []reql.Result) error {
+	if spec.path != "" && spec.path != "-" {
+		f, err := os.Create(spec.path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+
+		w = f
+	}
+
+	switch spec.format {
+	case reportJUnit:
+		return writeJUnit(w, results, a.mask)
+	case reportTAP:
+		return writeTAP(w, results, a.mask)
+	}
+
+	return fmt.Errorf("unknown report format %q", spec.format)
+}
+
+// The kinds of report test cases that did not pass.
+const (
+	caseFailure = "failure"
+	caseError   = "error"
+	caseSkipped = "skipped"
+)
+
+// reportCase is a single test case of a report. Every assertion of a reqfile becomes
+// a case. A reqfile that was skipped or could not be sent or captured from gets an
+// additional case describing why. The message of a case that did not pass has been
+// passed through the mask function.
+type reportCase struct {
+	name    string
+	kind    string
+	message string
+}
+
+func reportCases(result reql.Result, mask func(string) string) []reportCase {
+	var cases []reportCase
+	for _, a := range result.Assertions {
+		c := reportCase{name: a.Name}
+		if a.Err != nil {
+			c.kind, c.message = caseFailure, mask(a.Err.Error())
+		}
+		cases = append(cases, c)
+	}
+
+	var message string
+	if result.Err != nil {
+		message = mask(result.Err.Error())
+	}
+
+	switch {
+	case result.Skipped:
+		cases = append(cases, reportCase{name: "send", kind: caseSkipped, message: message})
+	case result.Err != nil && result.Exchange == nil:
+		cases = append(cases, reportCase{name: "send", kind: caseError, message: message})
+	case result.Err != nil:
+		cases = append(cases, reportCase{name: "capture", kind: caseError, message: message})
+	}
+
+	return cases
+}
+
+// duration returns how long the request of a result took.
+func duration(result reql.Result) time.Duration {
+	if result.Exchange == nil {
+		return 0
+	}
+
+	return result.Exchange.Timing.Total
+}
+
+// responseSnippet returns the status line and the beginning of the response body.
+// The body is masked before it is truncated so that a secret cut in half by the
+// truncation is still masked.
+func responseSnippet(ex *reql.Exchange, mask func(string) string) string {
+	if ex == nil || ex.Response == nil {
+		return ""
+	}
+
+	body := mask(string(ex.Body))
+	if len(body) > maxSnippetLen {
+		body = strings.ToValidUTF8(body[:maxSnippetLen], "") + "..."
+	}
+
+	return fmt.Sprintf("%s %s\n\n%s", ex.Response.Proto, mask(ex.Response.Status), body)
+}
+
+type junitTestSuites struct {
+	XMLName  xml.Name         `xml:"testsuites"`
+	Tests    int              `xml:"tests,attr"`
+	Failures int              `xml:"failures,attr"`
+	Errors   int              `xml:"errors,attr"`
+	Skipped  int              `xml:"skipped,attr"`
+	Time     string           `xml:"time,attr"`
+	Suites   []junitTestSuite `xml:"testsuite"`
+}
+
+type junitTestSuite struct {
+	Name      string          `xml:"name,attr"`
+	Tests     int             `xml:"tests,attr"`
+	Failures  int             `xml:"failures,attr"`
+	Errors    int             `xml:"errors,attr"`
+	Skipped   int             `xml:"skipped,attr"`
+	Time      string          `xml:"time,attr"`
+	Cases     []junitTestCase `xml:"testcase"`
+	SystemOut *junitOutput    `xml:"system-out"`
+}
+
+type junitTestCase struct {
+	Name      string        `xml:"name,attr"`
+	Classname string        `xml:"classname,attr"`
+	Time      string        `xml:"time,attr"`
+	Failure   *junitMessage `xml:"failure"`
+	Error     *junitMessage `xml:"error"`
+	Skipped   *junitMessage `xml:"skipped"`
+}
+
+// junitMessage and junitOutput use CDATA so that multiline text stays readable.
+type junitMessage struct {
+	Message string `xml:"message,attr"`
+	Text    string `xml:",cdata"`
+}
+
+type junitOutput struct {
+	Text string `xml:",cdata"`
+}
+
+// writeJUnit writes results as a JUnit XML document with a testsuite per reqfile and
+// a testcase per assertion. Messages and response snippets are passed through mask.
+func writeJUnit(w io.Writer, results []reql.Result, mask func(string) string) error {
+	doc := junitTestSuites{}
+	var total time.Duration
+	for _, result := range results {
+		d := duration(result)
+		total += d
+
+		snippet := responseSnippet(result.Exchange, mask)
+		suite := junitTestSuite{Name: result.Path, Time: seconds(d)}
+		if snippet != "" {
+			suite.SystemOut = &junitOutput{Text: snippet}
+		}
+		for _, c := range reportCases(result, mask) {
+			tc := junitTestCase{Name: c.name, Classname: result.Path, Time: seconds(d)}
+
+			var msg *junitMessage
+			if c.kind != "" {
+				msg = &junitMessage{Message: c.message, Text: c.message}
+				if snippet != "" {
+					msg.Text += "\n\n" + snippet
+				}
+			}
+
+			switch c.kind {
+			case caseFailure:
+				tc.Failure = msg
+				suite.Failures++
+			case caseError:
+				tc.Error = msg
+				suite.Errors++
+			case caseSkipped:
+				tc.Skipped = msg
+				suite.Skipped++
+			}
+
+			suite.Cases = append(suite.Cases, tc)
+			suite.Tests++
+		}
+
+		doc.Tests += suite.Tests
+		doc.Failures += suite.Failures
+		doc.Errors += suite.Errors
+		doc.Skipped += suite.Skipped
+		doc.Suites = append(doc.Suites, suite)
+	}
+	doc.Time = seconds(total)
+
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	enc := xml.NewEncoder(w)
+	enc.Indent("", "  ")
+	if err := enc.Encode(doc); err != nil {
+		return err
+	}
+
+	_, err := io.WriteString(w, "\n")
+	return err
+}
+
+// writeTAP writes results in the Test Anything Protocol version 13 with a test point
+// per assertion. Failures carry a YAML block with the message, duration, and
+// response snippet, all passed through mask.
+func writeTAP(w io.Writer, results []reql.Result, mask func(string) string) error {
+	var b strings.Builder
+	b.WriteString("TAP version 13\n")
+
+	n := 0
+	for _, result := range results {
+		n += len(reportCases(result, mask))
+	}
+	fmt.Fprintf(&b, "1..%d\n", n)
+
+	n = 0
+	for _, result := range results {
+		for _, c := range reportCases(result, mask) {
+			n++
+			description := tapEscape(result.Path + ": " + c.name)
+
+			switch c.kind {
+			case "":
+				fmt.Fprintf(&b, "ok %d - %s\n", n, description)
+				continue
+			case caseSkipped:
+				fmt.Fprintf(&b, "ok %d - %s # SKIP %s\n", n, description, tapEscape(c.message))
+				continue
+			}
+
+			fmt.Fprintf(&b, "not ok %d - %s\n", n, description)
+			b.WriteString("  ---\n")
+			fmt.Fprintf(&b, "  severity: %s\n", c.kind)
+			fmt.Fprintf(&b, "  message: %s\n", strconv.Quote(c.message))
+			fmt.Fprintf(&b, "  duration_ms: %g\n", reql.Millis(duration(result)))
+			if snippet := responseSnippet(result.Exchange, mask); snippet != "" {
+				b.WriteString("  response: |\n")
+				for _, line := range strings.Split(snippet, "\n") {
+					if line != "" {
+						b.WriteString("    " + line)
+					}
+					b.WriteString("\n")
+				}
+			}
+			b.WriteString("  ...\n")
+		}
+	}
+
+	_, err := io.WriteString(w, b.String())
+	return err
+}
+
+// tapEscape keeps a test point description on a single line and prevents it from
+// being read as a directive.
+func tapEscape(s string) string {
+	s = strings.ReplaceAll(s, "\n", " ")
+	return strings.ReplaceAll(s, "#", "\\#")
+}
+
+func seconds(d time.Duration) string {
+	return strconv.FormatFloat(d.Seconds(), 'f', 3, 64)
+}
@@ -0,0 +1,205 @@
+package cli
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mattmeyers/reql"
+)
+
+func testResults() []reql.Result {
+	ex := &reql.Exchange{
+		Response: &http.Response{Proto: "HTTP/1.1", Status: "200 OK", StatusCode: 200},
+		Body:     []byte("pong"),
+		Timing:   reql.Timing{Total: 1500 * time.Microsecond},
+	}
+
+	return []reql.Result{
+		{
+			Path:     "ping.hcl",
+			Exchange: ex,
+			Assertions: []reql.AssertionResult{
+				{Name: "Status"},
+				{Name: "Body #1", Err: errors.New("expected res.body == \"ping\"")},
+			},
+		},
+		{
+			Path: "broken.hcl",
+			Err:  errors.New("connection refused"),
+		},
+		{
+			Path:    "whoami.hcl",
+			Err:     errors.New("dependency broken.hcl errored"),
+			Skipped: true,
+		},
+	}
+}
+
+func noMask(s string) string { return s }
+
+func Test_parseReportSpec(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    reportSpec
+		wantErr bool
+	}{
+		{
+			name: "Format only",
+			s:    "tap",
+			want: reportSpec{format: reportTAP},
+		},
+		{
+			name: "Format and path",
+			s:    "junit=out/report.xml",
+			want: reportSpec{format: reportJUnit, path: "out/report.xml"},
+		},
+		{
+			name:    "Unknown format",
+			s:       "html=report.html",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := parseReportSpec(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("parseReportSpec() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseReportSpec() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_writeTAP(t *testing.T) {
+	var b strings.Builder
+	if err := writeTAP(&b, testResults(), noMask); err != nil {
+		t.Fatalf("writeTAP() error = %v", err)
+	}
+
+	want := `TAP version 13
+1..4
+ok 1 - ping.hcl: Status
+not ok 2 - ping.hcl: Body \#1
+  ---
+  severity: failure
+  message: "expected res.body == \"ping\""
+  duration_ms: 1.5
+  response: |
+    HTTP/1.1 200 OK
+
+    pong
+  ...
+not ok 3 - broken.hcl: send
+  ---
+  severity: error
+  message: "connection refused"
+  duration_ms: 0
+  ...
+ok 4 - whoami.hcl: send # SKIP dependency broken.hcl errored
+`
+	if got := b.String(); got != want {
+		t.Errorf("writeTAP() = %s, want %s", got, want)
+	}
+}
+
+func Test_writeJUnit(t *testing.T) {
+	var b strings.Builder
+	if err := writeJUnit(&b, testResults(), noMask); err != nil {
+		t.Fatalf("writeJUnit() error = %v", err)
+	}
+
+	got := b.String()
+	for _, want := range []string{
+		`<testsuites tests="4" failures="1" errors="1" skipped="1" time="0.002">`,
+		`<testsuite name="ping.hcl" tests="2" failures="1" errors="0" skipped="0" time="0.002">`,
+		`<testcase name="Status" classname="ping.hcl" time="0.002"></testcase>`,
+		`<failure message="expected res.body == &#34;ping&#34;"><![CDATA[expected res.body == "ping"` + "\n\nHTTP/1.1 200 OK\n\npong]]></failure>",
+		`<error message="connection refused"><![CDATA[connection refused]]></error>`,
+		`<skipped message="dependency broken.hcl errored"><![CDATA[dependency broken.hcl errored]]></skipped>`,
+		"<system-out><![CDATA[HTTP/1.1 200 OK\n\npong]]></system-out>",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("writeJUnit() = %s, want it to contain %s", got, want)
+		}
+	}
+}
+
+func Test_writeReports_MasksSecrets(t *testing.T) {
+	const secret = "s3cr3t"
+
+	results := []reql.Result{
+		{
+			Path: "login.hcl",
+			Exchange: &reql.Exchange{
+				Response: &http.Response{Proto: "HTTP/1.1", Status: "401 Unauthorized", StatusCode: 401},
+				Body:     []byte(`{"error":"bad token ` + secret + `"}`),
+			},
+			Assertions: []reql.AssertionResult{
+				{Name: "Status", Err: errors.New("token " + secret + " was rejected")},
+			},
+			Err: errors.New("capture token: no value in " + secret),
+		},
+		{
+			Path:    "whoami.hcl",
+			Err:     errors.New("dependency sent " + secret),
+			Skipped: true,
+		},
+	}
+	mask := func(s string) string { return reql.MaskSecrets(s, []string{secret}) }
+
+	tests := []struct {
+		name  string
+		write func(io.Writer, []reql.Result, func(string) string) error
+	}{
+		{name: "TAP", write: writeTAP},
+		{name: "JUnit", write: writeJUnit},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var b strings.Builder
+			if err := tt.write(&b, results, mask); err != nil {
+				t.Fatalf("write() error = %v", err)
+			}
+			if got := b.String(); strings.Contains(got, secret) {
+				t.Errorf("write() leaked the secret: %s", got)
+			}
+		})
+	}
+}
+
+func TestApp_writeReports(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		wantStdout bool
+	}{
+		{name: "Text output", output: outputText, wantStdout: true},
+		{name: "JSON output", output: outputJSON},
+		{name: "JSONL output", output: outputJSONL},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var stdout, stderr strings.Builder
+			a := &App{writer: &stdout, errWriter: &stderr}
+
+			opts := sendOptions{output: tt.output, reports: []reportSpec{{format: reportTAP}}}
+			a.writeReports(opts, testResults())
+
+			got, other := stdout.String(), stderr.String()
+			if !tt.wantStdout {
+				got, other = other, got
+			}
+			if !strings.HasPrefix(got, "TAP version 13") || other != "" {
+				t.Errorf("writeReports() stdout = %q, stderr = %q, want the report on stdout = %v", stdout.String(), stderr.String(), tt.wantStdout)
+			}
+		})
+	}
+}
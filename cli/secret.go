@@ -0,0 +1,143 @@
+package cli
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mattmeyers/reql"
+	"github.com/urfave/cli/v2"
+)
+
+func (a *App) secretCommand() *cli.Command {
+	return &cli.Command{
+		Name:  "secret",
+		Usage: "Manage the encrypted secrets that env values refer to as secret:name",
+		Subcommands: []*cli.Command{
+			{
+				Name:      "set",
+				Usage:     "Store a secret, reading the value from stdin if it is not given",
+				ArgsUsage: "name [value]",
+				Action:    a.handleSecretSet,
+			},
+			{
+				Name:      "get",
+				Usage:     "Print the value of a secret",
+				ArgsUsage: "name",
+				Action:    a.handleSecretGet,
+			},
+			{
+				Name:   "list",
+				Usage:  "List the names of all secrets",
+				Action: a.handleSecretList,
+			},
+			{
+				Name:      "delete",
+				Usage:     "Delete a secret",
+				ArgsUsage: "name",
+				Action:    a.handleSecretDelete,
+			},
+		},
+	}
+}
+
+func (a *App) handleSecretSet(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("secret name required")
+	}
+
+	value := c.Args().Get(1)
+	if c.Args().Len() < 2 {
+		line, err := a.reader.ReadString('\n')
+		if err != nil && line == "" {
+			return fmt.Errorf("could not read secret value: %v", err)
+		}
+		value = strings.TrimRight(line, "\r\n")
+	}
+
+	return a.config.SecretStore().Set(name, value)
+}
+
+func (a *App) handleSecretGet(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("secret name required")
+	}
+
+	value, err := a.config.SecretStore().Get(name)
+	if err != nil {
+		return err
+	}
+
+	fmt.Fprintln(a.writer, value)
+
+	return nil
+}
+
+func (a *App) handleSecretList(c *cli.Context) error {
+	names, err := a.config.SecretStore().List()
+	if err != nil {
+		return err
+	}
+
+	for _, name := range names {
+		fmt.Fprintln(a.writer, name)
+	}
+
+	return nil
+}
+
+func (a *App) handleSecretDelete(c *cli.Context) error {
+	name := c.Args().First()
+	if name == "" {
+		return errors.New("secret name required")
+	}
+
+	return a.config.SecretStore().Delete(name)
+}
+
+// loadEnv loads the current env and registers the values of its secrets so that
+// they are masked in output.
+func (a *App) loadEnv() (reql.Env, error) {
+	env, err := a.config.LoadEnv(a.env)
+	if err != nil {
+		return nil, err
+	}
+
+	a.secrets = env.SecretValues()
+
+	return env, nil
+}
+
+// mask hides the values of the secrets of the current env in s.
+func (a *App) mask(s string) string {
+	return reql.MaskSecrets(s, a.secrets)
+}
+
+// maskingLogger masks secret values in messages before passing them to the
+// wrapped logger.
+type maskingLogger struct {
+	reql.Logger
+	mask func(string) string
+}
+
+func (l maskingLogger) Debug(format string, args ...interface{}) {
+	l.Logger.Debug("%s", l.mask(fmt.Sprintf(format, args...)))
+}
+
+func (l maskingLogger) Info(format string, args ...interface{}) {
+	l.Logger.Info("%s", l.mask(fmt.Sprintf(format, args...)))
+}
+
+func (l maskingLogger) Warn(format string, args ...interface{}) {
+	l.Logger.Warn("%s", l.mask(fmt.Sprintf(format, args...)))
+}
+
+func (l maskingLogger) Error(format string, args ...interface{}) {
+	l.Logger.Error("%s", l.mask(fmt.Sprintf(format, args...)))
+}
+
+func (l maskingLogger) Fatal(format string, args ...interface{}) {
+	l.Logger.Fatal("%s", l.mask(fmt.Sprintf(format, args...)))
+}
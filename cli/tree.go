@@ -0,0 +1,45 @@
+package cli
+
+import (
+	"fmt"
+	"io"
+)
+
+// treeNode is an entry of a tree printed by the list command. Children are printed
+// in the order they were added.
+type treeNode struct {
+	label    string
+	children []*treeNode
+}
+
+// child returns the child with the given label, adding it if it does not exist.
+func (n *treeNode) child(label string) *treeNode {
+	for _, c := range n.children {
+		if c.label == label {
+			return c
+		}
+	}
+
+	c := &treeNode{label: label}
+	n.children = append(n.children, c)
+
+	return c
+}
+
+// render writes the node followed by its descendants using box drawing characters.
+func (n *treeNode) render(w io.Writer) {
+	fmt.Fprintln(w, n.label)
+	n.renderChildren(w, "")
+}
+
+func (n *treeNode) renderChildren(w io.Writer, prefix string) {
+	for i, c := range n.children {
+		branch, indent := "├── ", "│   "
+		if i == len(n.children)-1 {
+			branch, indent = "└── ", "    "
+		}
+
+		fmt.Fprintf(w, "%s%s%s\n", prefix, branch, c.label)
+		c.renderChildren(w, prefix+indent)
+	}
+}
@@ -0,0 +1,30 @@
+package cli
+
+import (
+	"strings"
+	"testing"
+)
+
+func Test_treeNode_render(t *testing.T) {
+	tree := &treeNode{label: "requests/"}
+	tree.child("ping.hcl (ping)")
+	users := tree.child("users/")
+	users.child("admin/").child("delete.hcl")
+	tree.child("users/").child("create.hcl")
+	tree.child("z.hcl")
+
+	var b strings.Builder
+	tree.render(&b)
+
+	want := `requests/
+├── ping.hcl (ping)
+├── users/
+│   ├── admin/
+│   │   └── delete.hcl
+│   └── create.hcl
+└── z.hcl
+`
+	if got := b.String(); got != want {
+		t.Errorf("treeNode.render() = %s, want %s", got, want)
+	}
+}
@@ -2,33 +2,345 @@ package reql
 
 import (
 	"bytes"
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
 	"net/http"
+	"net/http/httptrace"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/zclconf/go-cty/cty"
+	"golang.org/x/net/http2"
 )
 
+// ClientOptions configures how requests are sent. The zero value sends requests like
+// a zero value http.Client: without a timeout, following up to 10 redirects, and
+// using the proxy from the environment.
+//
+// Options can be set in the [client] table of the .reqrc file and overridden by a
+// client block in a reqfile. Unset options are left nil or empty so that overrides
+// only replace the options they set.
+type ClientOptions struct {
+	// Timeout limits the time a request may take, including reading the body, e.g.
+	// "5s". An empty string means no timeout.
+	Timeout string `toml:"timeout,omitempty" hcl:"timeout,optional"`
+	// FollowRedirects disables following redirects when set to false. The redirect
+	// response is then returned as is.
+	FollowRedirects *bool `toml:"follow_redirects,omitempty" hcl:"follow_redirects,optional"`
+	// MaxRedirects is the number of redirects followed before the request fails.
+	MaxRedirects *int `toml:"max_redirects,omitempty" hcl:"max_redirects,optional"`
+	// Proxy is the URL of the HTTP proxy to send requests through.
+	Proxy string `toml:"proxy,omitempty" hcl:"proxy,optional"`
+	// InsecureSkipVerify disables verification of the server's TLS certificate.
+	InsecureSkipVerify *bool `toml:"insecure_skip_verify,omitempty" hcl:"insecure_skip_verify,optional"`
+	// CAFile is a PEM bundle of certificate authorities to trust instead of the
+	// system pool.
+	CAFile string `toml:"ca_file,omitempty" hcl:"ca_file,optional"`
+	// CertFile and KeyFile are the PEM encoded client certificate and key used for
+	// mutual TLS. Both must be set together.
+	CertFile string `toml:"cert_file,omitempty" hcl:"cert_file,optional"`
+	KeyFile  string `toml:"key_file,omitempty" hcl:"key_file,optional"`
+}
+
+// Merge returns a copy of o with every option that is set in override replaced.
+func (o ClientOptions) Merge(override ClientOptions) ClientOptions {
+	if override.Timeout != "" {
+		o.Timeout = override.Timeout
+	}
+	if override.FollowRedirects != nil {
+		o.FollowRedirects = override.FollowRedirects
+	}
+	if override.MaxRedirects != nil {
+		o.MaxRedirects = override.MaxRedirects
+	}
+	if override.Proxy != "" {
+		o.Proxy = override.Proxy
+	}
+	if override.InsecureSkipVerify != nil {
+		o.InsecureSkipVerify = override.InsecureSkipVerify
+	}
+	if override.CAFile != "" {
+		o.CAFile = override.CAFile
+	}
+	if override.CertFile != "" {
+		o.CertFile = override.CertFile
+	}
+	if override.KeyFile != "" {
+		o.KeyFile = override.KeyFile
+	}
+
+	return o
+}
+
+// resolvePaths returns a copy of the options with relative file paths joined to dir.
+func (o ClientOptions) resolvePaths(dir string) *ClientOptions {
+	for _, path := range []*string{&o.CAFile, &o.CertFile, &o.KeyFile} {
+		if *path != "" && !filepath.IsAbs(*path) {
+			*path = filepath.Join(dir, *path)
+		}
+	}
+
+	return &o
+}
+
+// key returns a string that identifies the options for caching.
+func (o ClientOptions) key() string {
+	followRedirects, maxRedirects, insecure := "", "", ""
+	if o.FollowRedirects != nil {
+		followRedirects = strconv.FormatBool(*o.FollowRedirects)
+	}
+	if o.MaxRedirects != nil {
+		maxRedirects = strconv.Itoa(*o.MaxRedirects)
+	}
+	if o.InsecureSkipVerify != nil {
+		insecure = strconv.FormatBool(*o.InsecureSkipVerify)
+	}
+
+	return strings.Join([]string{
+		o.Timeout, followRedirects, maxRedirects, o.Proxy, insecure, o.CAFile, o.CertFile, o.KeyFile,
+	}, "\x00")
+}
+
+// Client sends requests. Requests that override the client options are sent through
+// a separate http.Client that is built once per distinct set of options. A Client is
+// safe for concurrent use.
 type Client struct {
-	client *http.Client
+	opts ClientOptions
+
+	mu      sync.Mutex
+	clients map[string]*http.Client
+}
+
+// NewClient constructs a client with the given default options. An error is
+// returned if the options are invalid, e.g. a CA bundle cannot be read.
+func NewClient(opts ClientOptions) (*Client, error) {
+	c := &Client{
+		opts:    opts,
+		clients: make(map[string]*http.Client),
+	}
+
+	if _, err := c.httpClient(nil, HTTPVersionDefault); err != nil {
+		return nil, err
+	}
+
+	return c, nil
+}
+
+func (c *Client) httpClient(override *ClientOptions, version string) (*http.Client, error) {
+	opts := c.opts
+	if override != nil {
+		opts = opts.Merge(*override)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	key := opts.key() + "\x00" + version
+	if client, ok := c.clients[key]; ok {
+		return client, nil
+	}
+
+	client, err := buildHTTPClient(opts, version)
+	if err != nil {
+		return nil, err
+	}
+	c.clients[key] = client
+
+	return client, nil
 }
 
-func NewClient() *Client {
-	return &Client{
-		client: &http.Client{},
+func buildHTTPClient(opts ClientOptions, version string) (*http.Client, error) {
+	client := &http.Client{}
+
+	if opts.Timeout != "" {
+		timeout, err := time.ParseDuration(opts.Timeout)
+		if err != nil {
+			return nil, fmt.Errorf("invalid timeout: %w", err)
+		}
+		client.Timeout = timeout
+	}
+
+	if opts.FollowRedirects != nil && !*opts.FollowRedirects {
+		client.CheckRedirect = func(*http.Request, []*http.Request) error {
+			return http.ErrUseLastResponse
+		}
+	} else if opts.MaxRedirects != nil {
+		max := *opts.MaxRedirects
+		client.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+			if len(via) > max {
+				return fmt.Errorf("stopped after %d redirects", max)
+			}
+			return nil
+		}
+	}
+
+	transport := http.DefaultTransport.(*http.Transport).Clone()
+
+	if opts.Proxy != "" {
+		proxy, err := url.Parse(opts.Proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid proxy: %w", err)
+		}
+		transport.Proxy = http.ProxyURL(proxy)
+	}
+
+	tlsConfig := &tls.Config{}
+	if opts.InsecureSkipVerify != nil {
+		tlsConfig.InsecureSkipVerify = *opts.InsecureSkipVerify
 	}
+
+	if opts.CAFile != "" {
+		pem, err := os.ReadFile(opts.CAFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not read CA bundle: %w", err)
+		}
+
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", opts.CAFile)
+		}
+		tlsConfig.RootCAs = pool
+	}
+
+	if opts.CertFile != "" || opts.KeyFile != "" {
+		if opts.CertFile == "" || opts.KeyFile == "" {
+			return nil, errors.New("cert_file and key_file must be set together")
+		}
+
+		cert, err := tls.LoadX509KeyPair(opts.CertFile, opts.KeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("could not load client certificate: %w", err)
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+
+	transport.TLSClientConfig = tlsConfig
+	client.Transport = transport
+
+	switch version {
+	case HTTPVersionDefault, HTTPVersion2:
+		// HTTP/2 is negotiated over TLS. Do rejects responses that were not sent
+		// with the requested version.
+	case HTTPVersion1:
+		// A non-nil, empty TLSNextProto disables HTTP/2.
+		transport.ForceAttemptHTTP2 = false
+		transport.TLSNextProto = make(map[string]func(string, *tls.Conn) http.RoundTripper)
+	case HTTPVersionH2C:
+		// HTTP/2 over cleartext with prior knowledge dials plain TCP connections in
+		// place of TLS. The proxy and TLS options do not apply.
+		client.Transport = &http2.Transport{
+			AllowHTTP: true,
+			DialTLSContext: func(ctx context.Context, network, addr string, _ *tls.Config) (net.Conn, error) {
+				var d net.Dialer
+				return d.DialContext(ctx, network, addr)
+			},
+		}
+	default:
+		return nil, fmt.Errorf("unsupported http_version %q", version)
+	}
+
+	return client, nil
 }
 
-func (c *Client) Do(req Request) (*http.Request, *http.Response, error) {
+// Exchange is a completed round trip. The response body has already been read into
+// Body so that it can be inspected more than once.
+type Exchange struct {
+	Request     Request
+	HTTPRequest *http.Request
+	Response    *http.Response
+	Body        []byte
+	Timing      Timing
+
+	jsonOnce sync.Once
+	json     cty.Value
+}
+
+// JSON returns the response body decoded as JSON, or null if the body is not valid
+// JSON. The body is only decoded once.
+func (ex *Exchange) JSON() cty.Value {
+	ex.jsonOnce.Do(func() {
+		ex.json = jsonVal(ex.Body)
+	})
+
+	return ex.json
+}
+
+// The HTTP versions a request can be sent with.
+const (
+	// HTTPVersionDefault negotiates HTTP/2 over TLS when the server supports it and
+	// falls back to HTTP/1.1.
+	HTTPVersionDefault = ""
+	// HTTPVersion1 always uses HTTP/1.1.
+	HTTPVersion1 = "1.1"
+	// HTTPVersion2 requires HTTP/2 negotiated over TLS.
+	HTTPVersion2 = "2"
+	// HTTPVersionH2C requires HTTP/2 over cleartext with prior knowledge.
+	HTTPVersionH2C = "h2c"
+)
+
+// Do sends req using the client options merged with req.Client and the protocol
+// selected by req.HTTPVersion. An error is returned if the requested protocol could
+// not be used.
+func (c *Client) Do(req Request) (*Exchange, error) {
+	client, err := c.httpClient(req.Client, req.HTTPVersion)
+	if err != nil {
+		return nil, err
+	}
+
 	httpReq, err := http.NewRequest(req.Method, req.URL, bytes.NewBufferString(req.Body))
 	if err != nil {
-		return nil, nil, err
+		return nil, err
+	}
+
+	switch {
+	case req.HTTPVersion == HTTPVersion2 && httpReq.URL.Scheme != "https":
+		return nil, fmt.Errorf("http_version %q requires an https URL, use %q for cleartext HTTP/2", HTTPVersion2, HTTPVersionH2C)
+	case req.HTTPVersion == HTTPVersionH2C && httpReq.URL.Scheme != "http":
+		return nil, fmt.Errorf("http_version %q requires an http URL", HTTPVersionH2C)
 	}
 
 	for key, value := range req.Headers {
 		httpReq.Header.Set(key, value)
 	}
 
-	res, err := c.client.Do(httpReq)
+	trace := newTimingTrace()
+	httpReq = httpReq.WithContext(httptrace.WithClientTrace(httpReq.Context(), trace.clientTrace()))
+
+	res, err := client.Do(httpReq)
+	if err != nil {
+		if req.HTTPVersion == HTTPVersion2 || req.HTTPVersion == HTTPVersionH2C {
+			return nil, fmt.Errorf("could not send request with http_version %q, the server may not support it: %w", req.HTTPVersion, err)
+		}
+		return nil, err
+	}
+	defer res.Body.Close()
+
+	if (req.HTTPVersion == HTTPVersion2 || req.HTTPVersion == HTTPVersionH2C) && res.ProtoMajor != 2 {
+		return nil, fmt.Errorf("server did not negotiate HTTP/2, got %s", res.Proto)
+	} else if req.HTTPVersion == HTTPVersion1 && res.ProtoMajor != 1 {
+		return nil, fmt.Errorf("server did not negotiate HTTP/1.1, got %s", res.Proto)
+	}
+
+	body, err := io.ReadAll(res.Body)
 	if err != nil {
-		return nil, nil, err
+		return nil, err
 	}
 
-	return httpReq, res, nil
+	return &Exchange{
+		Request:     req,
+		HTTPRequest: httpReq,
+		Response:    res,
+		Body:        body,
+		Timing:      trace.finish(),
+	}, nil
 }
@@ -0,0 +1,250 @@
+package reql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
+)
+
+func mustNewClient(t *testing.T) *Client {
+	t.Helper()
+
+	c, err := NewClient(ClientOptions{})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error = %v", err)
+	}
+
+	return c
+}
+
+func TestClientOptions_Merge(t *testing.T) {
+	yes, no, two := true, false, 2
+
+	base := ClientOptions{Timeout: "5s", FollowRedirects: &yes, Proxy: "http://proxy:3128"}
+	got := base.Merge(ClientOptions{Timeout: "1s", FollowRedirects: &no, MaxRedirects: &two})
+
+	if got.Timeout != "1s" || *got.FollowRedirects || *got.MaxRedirects != 2 || got.Proxy != "http://proxy:3128" {
+		t.Errorf("ClientOptions.Merge() = %+v", got)
+	}
+	if base.Timeout != "5s" || !*base.FollowRedirects {
+		t.Errorf("ClientOptions.Merge() modified the receiver")
+	}
+}
+
+func TestNewClientValidatesOptions(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ClientOptions
+		wantErr bool
+	}{
+		{
+			name:    "Zero value",
+			opts:    ClientOptions{},
+			wantErr: false,
+		},
+		{
+			name:    "Invalid timeout",
+			opts:    ClientOptions{Timeout: "soon"},
+			wantErr: true,
+		},
+		{
+			name:    "Missing CA bundle",
+			opts:    ClientOptions{CAFile: "does-not-exist.pem"},
+			wantErr: true,
+		},
+		{
+			name:    "Certificate without key",
+			opts:    ClientOptions{CertFile: "client.pem"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := NewClient(tt.opts); (err != nil) != tt.wantErr {
+				t.Errorf("NewClient() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestClient_DoRedirects(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/a":
+			http.Redirect(w, r, "/b", http.StatusFound)
+		case "/b":
+			http.Redirect(w, r, "/c", http.StatusFound)
+		default:
+			w.Write([]byte("done"))
+		}
+	}))
+	defer srv.Close()
+
+	no, one := false, 1
+
+	tests := []struct {
+		name     string
+		override *ClientOptions
+		wantCode int
+		wantErr  string
+	}{
+		{
+			name:     "Follows redirects by default",
+			wantCode: http.StatusOK,
+		},
+		{
+			name:     "Does not follow redirects",
+			override: &ClientOptions{FollowRedirects: &no},
+			wantCode: http.StatusFound,
+		},
+		{
+			name:     "Stops after max redirects",
+			override: &ClientOptions{MaxRedirects: &one},
+			wantErr:  "stopped after 1 redirects",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex, err := mustNewClient(t).Do(Request{Method: http.MethodGet, URL: srv.URL + "/a", Client: tt.override})
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Client.Do() error = %v, want %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Client.Do() unexpected error = %v", err)
+			}
+
+			if ex.Response.StatusCode != tt.wantCode {
+				t.Errorf("Client.Do() status = %d, want %d", ex.Response.StatusCode, tt.wantCode)
+			}
+		})
+	}
+}
+
+func TestClient_DoHTTPVersion(t *testing.T) {
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(r.Proto))
+	})
+
+	tlsSrv := httptest.NewUnstartedServer(handler)
+	tlsSrv.EnableHTTP2 = true
+	tlsSrv.StartTLS()
+	defer tlsSrv.Close()
+
+	h2cSrv := httptest.NewServer(h2c.NewHandler(handler, &http2.Server{}))
+	defer h2cSrv.Close()
+
+	plainSrv := httptest.NewServer(handler)
+	defer plainSrv.Close()
+
+	insecure := true
+	c, err := NewClient(ClientOptions{InsecureSkipVerify: &insecure})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		url       string
+		version   string
+		wantProto string
+		wantErr   bool
+	}{
+		{
+			name:      "Default negotiates HTTP/2 over TLS",
+			url:       tlsSrv.URL,
+			version:   HTTPVersionDefault,
+			wantProto: "HTTP/2.0",
+		},
+		{
+			name:      "HTTP/1.1 over TLS",
+			url:       tlsSrv.URL,
+			version:   HTTPVersion1,
+			wantProto: "HTTP/1.1",
+		},
+		{
+			name:      "HTTP/2 over TLS",
+			url:       tlsSrv.URL,
+			version:   HTTPVersion2,
+			wantProto: "HTTP/2.0",
+		},
+		{
+			name:    "HTTP/2 requires TLS",
+			url:     plainSrv.URL,
+			version: HTTPVersion2,
+			wantErr: true,
+		},
+		{
+			name:      "h2c with prior knowledge",
+			url:       h2cSrv.URL,
+			version:   HTTPVersionH2C,
+			wantProto: "HTTP/2.0",
+		},
+		{
+			name:    "h2c against an HTTP/1.1 server",
+			url:     plainSrv.URL,
+			version: HTTPVersionH2C,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown version",
+			url:     plainSrv.URL,
+			version: "3",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ex, err := c.Do(Request{Method: http.MethodGet, URL: tt.url, HTTPVersion: tt.version})
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Client.Do() error = %v, wantErr %v", err, tt.wantErr)
+			} else if err != nil {
+				return
+			}
+
+			if ex.Response.Proto != tt.wantProto || string(ex.Body) != tt.wantProto {
+				t.Errorf("Client.Do() used %s (server saw %s), want %s", ex.Response.Proto, ex.Body, tt.wantProto)
+			}
+		})
+	}
+}
+
+func TestClient_DoTiming(t *testing.T) {
+	srv := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte("ok"))
+	}))
+	defer srv.Close()
+
+	insecure := true
+	c, err := NewClient(ClientOptions{InsecureSkipVerify: &insecure})
+	if err != nil {
+		t.Fatalf("NewClient() unexpected error = %v", err)
+	}
+
+	ex, err := c.Do(Request{Method: http.MethodGet, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Client.Do() unexpected error = %v", err)
+	}
+
+	timing := ex.Timing
+	if timing.Connect <= 0 || timing.TLSHandshake <= 0 {
+		t.Errorf("Client.Do() timing = %s, want connect and TLS handshake phases", timing)
+	}
+	if timing.TimeToFirstByte <= 0 || timing.TimeToFirstByte > timing.Total {
+		t.Errorf("Client.Do() timing = %s, want 0 < ttfb <= total", timing)
+	}
+
+	ex, err = c.Do(Request{Method: http.MethodGet, URL: srv.URL})
+	if err != nil {
+		t.Fatalf("Client.Do() unexpected error = %v", err)
+	}
+
+	if ex.Timing.Connect != 0 || ex.Timing.TLSHandshake != 0 {
+		t.Errorf("Client.Do() timing = %s, want no connect or TLS phases on a reused connection", ex.Timing)
+	}
+}
@@ -9,7 +9,7 @@ import (
 
 func main() {
 	if err := run(os.Args); err != nil {
-		fmt.Println(err)
+		fmt.Fprintln(os.Stderr, err)
 		os.Exit(1)
 	}
 }
@@ -2,53 +2,300 @@ package reql
 
 import (
 	"errors"
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 
 	"github.com/BurntSushi/toml"
 )
 
 type Config struct {
-	Root         string            `toml:"root"`
-	DefaultEnv   string            `toml:"default_env"`
-	Aliases      map[string]string `toml:"aliases"`
-	Environments map[string]Env    `toml:"environments"`
+	Root         string            `toml:"root,omitempty"`
+	DefaultEnv   string            `toml:"default_env,omitempty"`
+	Aliases      map[string]string `toml:"aliases,omitempty"`
+	Environments map[string]Env    `toml:"environments,omitempty"`
+	Client       ClientOptions     `toml:"client,omitempty"`
+
+	// SecretsFile is the encrypted secret store. It defaults to .reqsecrets next to
+	// the config file.
+	SecretsFile string `toml:"secrets_file,omitempty"`
+	// SecretKeyFile holds the key of the secret store. It defaults to
+	// reql/secret.key in the user config directory.
+	SecretKeyFile string `toml:"secret_key_file,omitempty"`
+
+	// Autosave saves the config after every env change made in the REPL.
+	Autosave bool `toml:"autosave,omitempty"`
+
+	// path is the project config file. It is saved to unless an env came from
+	// another file.
+	path string
+	// dir is the directory containing the project config file.
+	dir string
+	// files are the config files that were loaded, from the user config to the
+	// project config.
+	files []string
+	// sources maps every setting to the file that set it. Settings are named by
+	// their TOML key, e.g. default_env, aliases.echo, environments.local, or
+	// client.timeout.
+	sources map[string]string
+	// saved is a copy of the environments as last loaded or saved, used to detect
+	// unsaved changes.
+	saved map[string]Env
+	// unknown holds an error for every key of the files that matches no setting.
+	unknown []*ConfigError
+	// secrets is the secret store, opened on first use.
+	secrets *SecretStore
+}
+
+// Env holds the values of an environment as decoded from TOML: strings, int64 and
+// float64 numbers, bools, times, and slices and maps of these.
+type Env map[string]interface{}
+
+// DefaultSecretsFile is the name of the secret store in the config directory.
+const DefaultSecretsFile = ".reqsecrets"
+
+// ExtendsKey is the reserved env key that names an env to inherit values from.
+const ExtendsKey = "extends"
+
+// EnvFileKey is the reserved env key that names a dotenv file to load into the env.
+const EnvFileKey = "env_file"
+
+// ConfigFileName is the name of the project config file. Earlier versions read
+// .reqlrc from the working directory instead.
+const ConfigFileName = ".reqrc"
+
+// UserConfigPath returns the path of the user config, reql/config.toml in the user
+// config directory, e.g. ~/.config/reql/config.toml on Linux.
+func UserConfigPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(dir, "reql", "config.toml"), nil
 }
 
-type Env map[string]string
+// FindConfig returns the path of the nearest ConfigFileName in dir or one of its
+// parents, or "" if there is none.
+func FindConfig(dir string) (string, error) {
+	dir, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+
+	for {
+		path := filepath.Join(dir, ConfigFileName)
+		if _, err := os.Stat(path); err == nil {
+			return path, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
 
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", nil
+		}
+		dir = parent
+	}
+}
+
+// LoadConfig loads the project config at path merged over the user config. If path
+// is empty, the nearest ConfigFileName in the working directory or its parents is
+// used, or ConfigFileName in the working directory if there is none. Settings of the
+// project config take precedence, and envs and aliases of the same name replace
+// those of the user config. Either file may be missing.
+func LoadConfig(path string) (*Config, error) {
+	if path == "" {
+		wd, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		path, err = FindConfig(wd)
+		if err != nil {
+			return nil, err
+		} else if path == "" {
+			path = ConfigFileName
+		} else if rel, err := filepath.Rel(wd, path); err == nil {
+			path = rel
+		}
+	}
+
+	project, err := ParseConfig(path)
+	if err != nil {
+		return nil, err
+	}
+
+	userPath, err := UserConfigPath()
+	if err != nil {
+		return project, nil
+	}
+
+	user, err := ParseConfig(userPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return mergeConfig(user, project), nil
+}
+
+// ParseConfig parses the config file at path. Relative paths in the file are
+// resolved against its directory. A missing file results in an empty config that
+// is saved to path. Syntax errors are returned as a *ConfigError; other problems,
+// including unknown keys, are reported by Validate.
 func ParseConfig(path string) (*Config, error) {
 	if path == "" {
-		path = "./.reqrc"
+		path = "./" + ConfigFileName
 	}
 
-	var c Config
-	_, err := toml.DecodeFile(path, &c)
+	src, err := os.ReadFile(path)
 	if os.IsNotExist(err) {
-		return defaultConfig(), nil
+		return defaultConfig(path), nil
 	} else if err != nil {
 		return nil, err
 	}
 
+	var c Config
+	md, err := toml.Decode(string(src), &c)
+	if err != nil {
+		return nil, parseError(path, err)
+	}
+	c.unknown = unknownKeys(path, src, md)
+
+	c.path = path
+	c.dir = filepath.Dir(path)
+	c.files = []string{path}
+
+	if c.Root != "" && !filepath.IsAbs(c.Root) {
+		c.Root = filepath.Join(c.dir, c.Root)
+	}
 	for k, v := range c.Aliases {
+		if !filepath.IsAbs(v) {
+			v = filepath.Join(c.dir, v)
+		}
 		c.Aliases[k] = filepath.Clean(v)
 	}
+	for _, p := range []*string{&c.SecretsFile, &c.SecretKeyFile} {
+		if *p != "" && !filepath.IsAbs(*p) {
+			*p = filepath.Join(c.dir, *p)
+		}
+	}
+	c.Client = *c.Client.resolvePaths(c.dir)
+
+	c.sources = make(map[string]string)
+	for _, key := range md.Keys() {
+		switch len(key) {
+		case 1:
+			if key[0] != "aliases" && key[0] != "environments" && key[0] != "client" {
+				c.sources[key[0]] = path
+			}
+		case 2:
+			c.sources[key[0]+"."+key[1]] = path
+		}
+	}
+
+	c.saved = copyEnvironments(c.Environments)
 
 	return &c, nil
 }
 
-func defaultConfig() *Config {
+func defaultConfig(path string) *Config {
 	return &Config{
 		Aliases:      map[string]string{},
 		Environments: map[string]Env{},
+		path:         path,
+		dir:          filepath.Dir(path),
+		sources:      map[string]string{},
+		saved:        map[string]Env{},
 	}
 }
 
+// mergeConfig returns base with the settings of over applied. The result is saved
+// to the path of over.
+func mergeConfig(base, over *Config) *Config {
+	c := *base
+	c.path, c.dir = over.path, over.dir
+	c.files = append(append([]string(nil), base.files...), over.files...)
+	c.unknown = append(append([]*ConfigError(nil), base.unknown...), over.unknown...)
+
+	c.sources = make(map[string]string, len(base.sources)+len(over.sources))
+	for k, v := range base.sources {
+		c.sources[k] = v
+	}
+	for k, v := range over.sources {
+		c.sources[k] = v
+	}
+
+	if _, ok := over.sources["root"]; ok {
+		c.Root = over.Root
+	}
+	if _, ok := over.sources["default_env"]; ok {
+		c.DefaultEnv = over.DefaultEnv
+	}
+	if _, ok := over.sources["secrets_file"]; ok {
+		c.SecretsFile = over.SecretsFile
+	}
+	if _, ok := over.sources["secret_key_file"]; ok {
+		c.SecretKeyFile = over.SecretKeyFile
+	}
+	if _, ok := over.sources["autosave"]; ok {
+		c.Autosave = over.Autosave
+	}
+
+	c.Aliases = make(map[string]string, len(base.Aliases)+len(over.Aliases))
+	for k, v := range base.Aliases {
+		c.Aliases[k] = v
+	}
+	for k, v := range over.Aliases {
+		c.Aliases[k] = v
+	}
+
+	c.Environments = make(map[string]Env, len(base.Environments)+len(over.Environments))
+	for k, v := range base.Environments {
+		c.Environments[k] = v
+	}
+	for k, v := range over.Environments {
+		c.Environments[k] = v
+	}
+	c.saved = copyEnvironments(c.Environments)
+
+	c.Client = base.Client.Merge(over.Client)
+
+	return &c
+}
+
+// Files returns the config files that were loaded, from the user config to the
+// project config. Missing files are not included.
+func (c *Config) Files() []string {
+	return c.files
+}
+
+// Source returns the file that set a setting, named by its TOML key as in
+// default_env, aliases.echo, environments.local, or client.timeout. It returns ""
+// for settings that were not set in a file.
+func (c *Config) Source(key string) string {
+	return c.sources[key]
+}
+
+// envDir returns the directory of the file that defined the named env. Relative
+// paths in the env are resolved against it.
+func (c *Config) envDir(name string) string {
+	if path, ok := c.sources["environments."+name]; ok {
+		return filepath.Dir(path)
+	}
+
+	return c.dir
+}
+
 func (c *Config) NewEnv(env string) error {
 	if _, ok := c.Environments[env]; ok {
 		return errors.New("env already exists")
 	}
 
+	if c.Environments == nil {
+		c.Environments = make(map[string]Env)
+	}
 	c.Environments[env] = make(Env)
 
 	return nil
@@ -75,3 +322,130 @@ func (c *Config) DeleteEnvValue(env, key string) error {
 
 	return nil
 }
+
+// LoadEnv returns the values of the named env that are exposed to reqfiles, merged
+// with the envs it extends as described by ResolveEnv. If the env sets EnvFileKey,
+// the dotenv file it names is read and its values are merged into the env. Values
+// set in the config file take precedence over the dotenv file. A relative env file
+// path is resolved against the directory of the config file that set it. String values starting
+// with SecretPrefix become Secrets, which are read from the secret store when a
+// reqfile is parsed. An unknown env has no values.
+func (c *Config) LoadEnv(name string) (Env, error) {
+	envMap, sources, err := c.ResolveEnv(name)
+	if err != nil || envMap == nil {
+		return nil, err
+	}
+
+	env := make(Env, len(envMap))
+	if v, ok := envMap[EnvFileKey]; ok {
+		path, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("env %s: %s must be a string", name, EnvFileKey)
+		}
+
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(c.envDir(sources[EnvFileKey]), path)
+		}
+
+		values, err := ReadDotenv(path)
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", name, err)
+		}
+
+		for k, v := range values {
+			env[k] = v
+		}
+	}
+
+	for k, v := range envMap {
+		if k != EnvFileKey {
+			env[k] = v
+		}
+	}
+
+	return env.withSecrets(c.SecretStore()), nil
+}
+
+// ResolveEnv merges the named env with the envs it extends. An env extends another
+// by setting ExtendsKey to its name, and inheritance may span any number of levels.
+// Values of an env replace those of the envs it extends, including tables, which are
+// not merged. The returned sources map every key to the env its value came from.
+// The ExtendsKey itself is not part of the merged env. An unknown env has no values.
+func (c *Config) ResolveEnv(name string) (Env, map[string]string, error) {
+	layers, err := c.EnvLayers(name)
+	if err != nil || layers == nil {
+		return nil, nil, err
+	}
+
+	env := make(Env)
+	sources := make(map[string]string)
+	for i := len(layers) - 1; i >= 0; i-- {
+		for k, v := range c.Environments[layers[i]] {
+			if k != ExtendsKey {
+				env[k] = v
+				sources[k] = layers[i]
+			}
+		}
+	}
+
+	return env, sources, nil
+}
+
+// EnvLayers returns the named env followed by the envs it extends, from the nearest
+// to the most distant. It returns an error if an extended env does not exist or the
+// envs extend each other in a cycle. An unknown env has no layers.
+func (c *Config) EnvLayers(name string) ([]string, error) {
+	if _, ok := c.Environments[name]; !ok {
+		return nil, nil
+	}
+
+	layers := []string{name}
+	for env := name; ; {
+		v, ok := c.Environments[env][ExtendsKey]
+		if !ok {
+			return layers, nil
+		}
+
+		parent, ok := v.(string)
+		if !ok {
+			return nil, fmt.Errorf("env %s: %s must be a string", env, ExtendsKey)
+		} else if _, ok := c.Environments[parent]; !ok {
+			return nil, fmt.Errorf("env %s: extends unknown env %s", env, parent)
+		}
+
+		for _, layer := range layers {
+			if layer == parent {
+				chain := append(layers, parent)
+				return nil, fmt.Errorf("env %s: inheritance cycle %s", name, strings.Join(chain, " -> "))
+			}
+		}
+
+		layers = append(layers, parent)
+		env = parent
+	}
+}
+
+// SecretStore returns the store that secret references in envs are read from.
+func (c *Config) SecretStore() *SecretStore {
+	if c.secrets != nil {
+		return c.secrets
+	}
+
+	path := c.SecretsFile
+	if path == "" {
+		path = filepath.Join(c.dir, DefaultSecretsFile)
+	}
+
+	keyPath := c.SecretKeyFile
+	if keyPath == "" {
+		if dir, err := os.UserConfigDir(); err == nil {
+			keyPath = filepath.Join(dir, "reql", "secret.key")
+		} else {
+			keyPath = filepath.Join(c.dir, ".reqsecret.key")
+		}
+	}
+
+	c.secrets = NewSecretStore(path, keyPath)
+
+	return c.secrets
+}
@@ -0,0 +1,427 @@
+package reql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestConfig_LoadEnv(t *testing.T) {
+	dir := t.TempDir()
+	config := `
+[environments.local]
+base_url = "http://localhost:8080"
+user = "config"
+env_file = ".env"
+
+[environments.broken]
+env_file = "missing.env"
+`
+	if err := os.WriteFile(filepath.Join(dir, ".reqrc"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(dir, ".env"), []byte("TOKEN=s3cr3t\nuser=dotenv\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseConfig(filepath.Join(dir, ".reqrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := c.LoadEnv("local")
+	if err != nil {
+		t.Fatalf("Config.LoadEnv() error = %v", err)
+	}
+	want := Env{"base_url": "http://localhost:8080", "user": "config", "TOKEN": "s3cr3t"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Config.LoadEnv() = %v, want %v", got, want)
+	}
+
+	if _, err := c.LoadEnv("broken"); err == nil {
+		t.Error("Config.LoadEnv() error = nil, want an error for a missing env file")
+	}
+
+	if got, err := c.LoadEnv("unknown"); err != nil || len(got) != 0 {
+		t.Errorf("Config.LoadEnv() = %v, %v, want no values", got, err)
+	}
+}
+
+func TestConfig_StructuredEnv(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".reqrc")
+	config := `
+[environments.local]
+base_url = "http://localhost:8080"
+retries = 3
+ratio = 0.5
+verbose = true
+ids = [1, 2, 3]
+mixed = ["a", 1]
+empty = []
+
+[environments.local.db]
+host = "localhost"
+port = 5432
+
+[[environments.local.users]]
+name = "admin"
+`
+	if err := os.WriteFile(path, []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseConfig(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	env, err := c.LoadEnv("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := newEvalContext(env, nil, "")
+
+	tests := []struct {
+		expr string
+		want cty.Value
+	}{
+		{expr: `env.base_url`, want: cty.StringVal("http://localhost:8080")},
+		{expr: `env.retries + 1`, want: cty.NumberIntVal(4)},
+		{expr: `env.ratio * 2`, want: cty.NumberIntVal(1)},
+		{expr: `env.verbose`, want: cty.True},
+		{expr: `env.ids[1]`, want: cty.NumberIntVal(2)},
+		{expr: `length(env.ids)`, want: cty.NumberIntVal(3)},
+		{expr: `env.mixed[0]`, want: cty.StringVal("a")},
+		{expr: `length(env.empty)`, want: cty.NumberIntVal(0)},
+		{expr: `"${env.db.host}:${env.db.port}"`, want: cty.StringVal("localhost:5432")},
+		{expr: `env.users[0].name`, want: cty.StringVal("admin")},
+	}
+	for _, tt := range tests {
+		t.Run(tt.expr, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			got, diags := expr.Value(ctx)
+			if diags.HasErrors() {
+				t.Fatalf("Value() error = %v", diags)
+			}
+			if !got.Equals(tt.want).True() {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_Save(t *testing.T) {
+	const src = `# Shared settings.
+root = "./requests"
+
+[environments.local]
+# Where the example server listens.
+base_url = "http://localhost:8080" # default port
+user     = "alice"
+token    = "old"
+
+[environments.prod]
+base_url = "https://example.com"
+`
+	tests := []struct {
+		name string
+		src  string
+		edit func(c *Config)
+		want string
+	}{
+		{
+			name: "Edits keep comments and order",
+			src:  src,
+			edit: func(c *Config) {
+				c.SetEnvValue("local", "user", "bob")
+				c.SetEnvValue("local", "timeout", "5s")
+				c.DeleteEnvValue("local", "token")
+			},
+			want: `# Shared settings.
+root = "./requests"
+
+[environments.local]
+# Where the example server listens.
+base_url = "http://localhost:8080" # default port
+user = "bob"
+timeout = "5s"
+
+[environments.prod]
+base_url = "https://example.com"
+`,
+		},
+		{
+			name: "New env is appended",
+			src:  src,
+			edit: func(c *Config) {
+				c.NewEnv("staging")
+				c.SetEnvValue("staging", "base_url", "https://staging.example.com")
+			},
+			want: src + `
+[environments.staging]
+base_url = "https://staging.example.com"
+`,
+		},
+		{
+			name: "Inline env is rewritten",
+			src:  "# Dropped.\nenvironments = { local = { user = \"alice\" } }\n",
+			edit: func(c *Config) {
+				c.SetEnvValue("local", "user", "bob")
+			},
+			want: "[environments]\n  [environments.local]\n    user = \"bob\"\n",
+		},
+		{
+			name: "Missing file is created",
+			edit: func(c *Config) {
+				c.NewEnv("local")
+				c.SetEnvValue("local", "user", "bob")
+			},
+			want: "[environments.local]\nuser = \"bob\"\n",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), ".reqrc")
+			if tt.src != "" {
+				if err := os.WriteFile(path, []byte(tt.src), 0o644); err != nil {
+					t.Fatal(err)
+				}
+			}
+
+			c, err := ParseConfig(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if c.Unsaved() {
+				t.Fatal("Config.Unsaved() = true before any change")
+			}
+
+			tt.edit(c)
+			if !c.Unsaved() {
+				t.Fatal("Config.Unsaved() = false after a change")
+			}
+
+			if err := c.Save(); err != nil {
+				t.Fatalf("Config.Save() error = %v", err)
+			}
+			if c.Unsaved() {
+				t.Error("Config.Unsaved() = true after saving")
+			}
+
+			got, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("Config.Save() wrote\n%s\nwant\n%s", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestConfig_ResolveEnv(t *testing.T) {
+	c := &Config{Environments: map[string]Env{
+		"base":    {"base_url": "http://localhost", "timeout": "5s", "user": "base"},
+		"staging": {ExtendsKey: "base", "base_url": "https://staging.example.com"},
+		"prod":    {ExtendsKey: "staging", "user": "prod"},
+		"loop_a":  {ExtendsKey: "loop_b"},
+		"loop_b":  {ExtendsKey: "loop_a"},
+		"orphan":  {ExtendsKey: "missing"},
+	}}
+
+	tests := []struct {
+		name        string
+		env         string
+		want        Env
+		wantSources map[string]string
+		wantErr     string
+	}{
+		{
+			name:        "Without a parent",
+			env:         "base",
+			want:        Env{"base_url": "http://localhost", "timeout": "5s", "user": "base"},
+			wantSources: map[string]string{"base_url": "base", "timeout": "base", "user": "base"},
+		},
+		{
+			name:        "Multiple levels",
+			env:         "prod",
+			want:        Env{"base_url": "https://staging.example.com", "timeout": "5s", "user": "prod"},
+			wantSources: map[string]string{"base_url": "staging", "timeout": "base", "user": "prod"},
+		},
+		{
+			name:    "Cycle",
+			env:     "loop_a",
+			wantErr: "inheritance cycle loop_a -> loop_b -> loop_a",
+		},
+		{
+			name:    "Unknown parent",
+			env:     "orphan",
+			wantErr: "extends unknown env missing",
+		},
+		{
+			name: "Unknown env",
+			env:  "missing",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, sources, err := c.ResolveEnv(tt.env)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("Config.ResolveEnv() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("Config.ResolveEnv() unexpected error = %v", err)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Config.ResolveEnv() = %v, want %v", got, tt.want)
+			}
+			if !reflect.DeepEqual(sources, tt.wantSources) {
+				t.Errorf("Config.ResolveEnv() sources = %v, want %v", sources, tt.wantSources)
+			}
+		})
+	}
+}
+
+func TestFindConfig(t *testing.T) {
+	dir := writeTree(t, ".reqrc", "api/users/create.hcl")
+
+	got, err := FindConfig(filepath.Join(dir, "api", "users"))
+	if err != nil {
+		t.Fatalf("FindConfig() error = %v", err)
+	}
+	if want := filepath.Join(dir, ".reqrc"); got != want {
+		t.Errorf("FindConfig() = %q, want %q", got, want)
+	}
+
+	if got, err := FindConfig(writeTree(t, "ping.hcl")); err != nil || got != "" {
+		t.Errorf("FindConfig() = %q, %v, want no config", got, err)
+	}
+}
+
+func TestLoadConfig(t *testing.T) {
+	home := t.TempDir()
+	t.Setenv("XDG_CONFIG_HOME", home)
+
+	userPath := filepath.Join(home, "reql", "config.toml")
+	user := `
+default_env = "mine"
+
+[aliases]
+ping = "/srv/ping.hcl"
+
+[environments.mine]
+extends = "local"
+token = "abc"
+
+[environments.local]
+base_url = "http://localhost:1234"
+
+[client]
+timeout = "3s"
+proxy = "http://proxy:3128"
+`
+	if err := os.MkdirAll(filepath.Dir(userPath), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(userPath, []byte(user), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	dir := t.TempDir()
+	projectPath := filepath.Join(dir, ".reqrc")
+	project := `
+root = "requests"
+
+[aliases]
+echo = "requests/echo.hcl"
+
+[environments.local]
+base_url = "http://localhost:8080"
+
+[client]
+timeout = "10s"
+ca_file = "certs/ca.pem"
+`
+	if err := os.WriteFile(projectPath, []byte(project), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := LoadConfig(projectPath)
+	if err != nil {
+		t.Fatalf("LoadConfig() error = %v", err)
+	}
+
+	if want := []string{userPath, projectPath}; !reflect.DeepEqual(c.Files(), want) {
+		t.Errorf("Config.Files() = %v, want %v", c.Files(), want)
+	}
+	if c.DefaultEnv != "mine" || c.Root != filepath.Join(dir, "requests") {
+		t.Errorf("LoadConfig() DefaultEnv, Root = %q, %q", c.DefaultEnv, c.Root)
+	}
+
+	wantAliases := map[string]string{"ping": "/srv/ping.hcl", "echo": filepath.Join(dir, "requests/echo.hcl")}
+	if !reflect.DeepEqual(c.Aliases, wantAliases) {
+		t.Errorf("LoadConfig() Aliases = %v, want %v", c.Aliases, wantAliases)
+	}
+
+	if c.Client.Timeout != "10s" || c.Client.Proxy != "http://proxy:3128" || c.Client.CAFile != filepath.Join(dir, "certs/ca.pem") {
+		t.Errorf("LoadConfig() Client = %+v", c.Client)
+	}
+
+	env, err := c.LoadEnv("mine")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if want := (Env{"base_url": "http://localhost:8080", "token": "abc"}); !reflect.DeepEqual(env, want) {
+		t.Errorf("Config.LoadEnv() = %v, want %v", env, want)
+	}
+
+	sources := map[string]string{
+		"default_env":        userPath,
+		"root":               projectPath,
+		"aliases.ping":       userPath,
+		"environments.local": projectPath,
+		"environments.mine":  userPath,
+		"client.timeout":     projectPath,
+		"client.proxy":       userPath,
+	}
+	for key, want := range sources {
+		if got := c.Source(key); got != want {
+			t.Errorf("Config.Source(%q) = %q, want %q", key, got, want)
+		}
+	}
+
+	// Envs are saved to the file that defined them.
+	c.SetEnvValue("mine", "token", "xyz")
+	c.NewEnv("staging")
+	if err := c.Save(); err != nil {
+		t.Fatalf("Config.Save() error = %v", err)
+	}
+
+	b, err := os.ReadFile(userPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), `token = "xyz"`) || strings.Contains(string(b), "staging") {
+		t.Errorf("Config.Save() wrote the user config\n%s", b)
+	}
+
+	b, err = os.ReadFile(projectPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(b), "[environments.staging]") || strings.Contains(string(b), "xyz") {
+		t.Errorf("Config.Save() wrote the project config\n%s", b)
+	}
+}
@@ -0,0 +1,621 @@
+package reql
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// Path returns the path of the project config file, which new envs are saved to.
+func (c *Config) Path() string {
+	return c.path
+}
+
+// Unsaved reports whether the environments were changed since the config was loaded
+// or last saved.
+func (c *Config) Unsaved() bool {
+	return !reflect.DeepEqual(c.saved, c.Environments)
+}
+
+// Save writes the environments back to the config files that defined them. New
+// envs are written to the project config. Only the lines of the environments that
+// changed are edited, so comments, formatting, and the order of other keys are kept.
+// When the changes cannot be expressed as line edits, such as for an env defined as
+// an inline table, the whole file is rewritten without its comments. A missing
+// project config is created.
+func (c *Config) Save() error {
+	files := []string{c.path}
+	for _, file := range c.files {
+		if file != c.path {
+			files = append(files, file)
+		}
+	}
+
+	for _, file := range files {
+		before, after := c.fileEnvironments(c.saved, file), c.fileEnvironments(c.Environments, file)
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		if err := saveEnvironments(file, before, after); err != nil {
+			return err
+		}
+	}
+
+	for name := range c.Environments {
+		if _, ok := c.sources["environments."+name]; !ok {
+			c.sources["environments."+name] = c.path
+		}
+	}
+	c.saved = copyEnvironments(c.Environments)
+
+	return nil
+}
+
+// fileEnvironments returns the envs that belong to file. Envs that were not loaded
+// from a file belong to the project config.
+func (c *Config) fileEnvironments(envs map[string]Env, file string) map[string]Env {
+	m := make(map[string]Env)
+	for name, env := range envs {
+		source, ok := c.sources["environments."+name]
+		if !ok {
+			source = c.path
+		}
+
+		if source == file {
+			m[name] = env
+		}
+	}
+
+	return m
+}
+
+// saveEnvironments replaces the old environments of the config file at path with the
+// new ones.
+func saveEnvironments(path string, old, new map[string]Env) error {
+	src, err := os.ReadFile(path)
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	mode := os.FileMode(0o644)
+	if info, err := os.Stat(path); err == nil {
+		mode = info.Mode().Perm()
+	}
+
+	out, ok := editEnvironments(src, old, new)
+	if !ok {
+		doc := make(map[string]interface{})
+		if _, err := toml.Decode(string(src), &doc); err != nil {
+			return err
+		}
+		doc["environments"] = new
+
+		var buf bytes.Buffer
+		if err := toml.NewEncoder(&buf).Encode(doc); err != nil {
+			return err
+		}
+		out = buf.Bytes()
+	}
+
+	return os.WriteFile(path, out, mode)
+}
+
+// WriteEffective writes the merged config as TOML. Every setting is followed by a
+// comment naming the file it came from, and the loaded files are listed first.
+// Relative paths are written as resolved against the directories of their files.
+func (c *Config) WriteEffective(w io.Writer) error {
+	if len(c.files) == 0 {
+		fmt.Fprint(w, "# No config files found.\n")
+	} else {
+		fmt.Fprint(w, "# Loaded from:\n")
+		for _, file := range c.files {
+			fmt.Fprintf(w, "#   %s\n", file)
+		}
+	}
+
+	setting := func(key, source string, v interface{}) error {
+		value, err := tomlValue(v)
+		if err != nil {
+			return err
+		}
+
+		if source == "" {
+			fmt.Fprintf(w, "%s = %s\n", tomlKey(key), value)
+		} else {
+			fmt.Fprintf(w, "%s = %s  # %s\n", tomlKey(key), value, source)
+		}
+
+		return nil
+	}
+
+	fmt.Fprint(w, "\n")
+	top := []struct {
+		key   string
+		value interface{}
+	}{
+		{"root", c.Root},
+		{"default_env", c.DefaultEnv},
+		{"secrets_file", c.SecretsFile},
+		{"secret_key_file", c.SecretKeyFile},
+		{"autosave", c.Autosave},
+	}
+	for _, s := range top {
+		if source, ok := c.sources[s.key]; ok {
+			if err := setting(s.key, source, s.value); err != nil {
+				return err
+			}
+		}
+	}
+
+	if len(c.Aliases) > 0 {
+		fmt.Fprint(w, "\n[aliases]\n")
+		names := make([]string, 0, len(c.Aliases))
+		for name := range c.Aliases {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+
+		for _, name := range names {
+			if err := setting(name, c.sources["aliases."+name], c.Aliases[name]); err != nil {
+				return err
+			}
+		}
+	}
+
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		fmt.Fprintf(w, "\n[environments.%s]", tomlKey(name))
+		if source, ok := c.sources["environments."+name]; ok {
+			fmt.Fprintf(w, "  # %s", source)
+		}
+		fmt.Fprint(w, "\n")
+
+		env := c.Environments[name]
+		for _, key := range sortedKeys(env) {
+			if err := setting(key, "", env[key]); err != nil {
+				return err
+			}
+		}
+	}
+
+	client := reflect.ValueOf(c.Client)
+	header := false
+	for i := 0; i < client.NumField(); i++ {
+		field := client.Field(i)
+		if field.IsZero() {
+			continue
+		}
+		if field.Kind() == reflect.Ptr {
+			field = field.Elem()
+		}
+
+		if !header {
+			fmt.Fprint(w, "\n[client]\n")
+			header = true
+		}
+
+		key := strings.SplitN(client.Type().Field(i).Tag.Get("toml"), ",", 2)[0]
+		if err := setting(key, c.sources["client."+key], field.Interface()); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+func copyEnvironments(envs map[string]Env) map[string]Env {
+	if envs == nil {
+		return nil
+	}
+
+	cp := make(map[string]Env, len(envs))
+	for name, env := range envs {
+		cp[name] = make(Env, len(env))
+		for k, v := range env {
+			cp[name][k] = v
+		}
+	}
+
+	return cp
+}
+
+// editEnvironments applies the difference between the old and new environments to
+// the TOML document src. It reports false if an edit could not be made safely.
+func editEnvironments(src []byte, old, new map[string]Env) ([]byte, bool) {
+	doc := newTomlDoc(src)
+
+	names := make([]string, 0, len(new))
+	for name := range new {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		before, after := old[name], new[name]
+		if reflect.DeepEqual(before, after) {
+			continue
+		}
+
+		header := doc.findTable("environments", name)
+		if header < 0 {
+			if _, ok := old[name]; ok {
+				return nil, false
+			} else if !doc.appendTable(after, "environments", name) {
+				return nil, false
+			}
+			continue
+		}
+
+		for _, key := range sortedKeys(before) {
+			if _, ok := after[key]; !ok && !doc.deleteKey(header, key) {
+				return nil, false
+			}
+		}
+
+		for _, key := range sortedKeys(after) {
+			prev, existed := before[key]
+			if existed && reflect.DeepEqual(prev, after[key]) {
+				continue
+			} else if !doc.setKey(header, key, after[key], existed) {
+				return nil, false
+			}
+		}
+	}
+
+	out := []byte(strings.Join(doc.lines, ""))
+
+	// Make sure the edited document holds exactly the new environments.
+	var check struct {
+		Environments map[string]Env `toml:"environments"`
+	}
+	if _, err := toml.Decode(string(out), &check); err != nil {
+		return nil, false
+	} else if len(new) > 0 && !reflect.DeepEqual(check.Environments, new) {
+		return nil, false
+	}
+
+	return out, true
+}
+
+// tomlDoc is a TOML document split into lines, newlines included.
+type tomlDoc struct {
+	lines []string
+}
+
+func newTomlDoc(src []byte) *tomlDoc {
+	doc := &tomlDoc{lines: strings.SplitAfter(string(src), "\n")}
+	if doc.lines[len(doc.lines)-1] == "" {
+		doc.lines = doc.lines[:len(doc.lines)-1]
+	}
+
+	return doc
+}
+
+// findTable returns the index of the header line of the table at path, or -1.
+func (d *tomlDoc) findTable(path ...string) int {
+	for i, line := range d.lines {
+		if keys, ok := parseTableHeader(line); ok && reflect.DeepEqual(keys, path) {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// tableEnd returns the index of the line after the last line of the table whose
+// header is at index header.
+func (d *tomlDoc) tableEnd(header int) int {
+	for i := header + 1; i < len(d.lines); i++ {
+		if strings.HasPrefix(strings.TrimSpace(d.lines[i]), "[") {
+			return i
+		}
+	}
+
+	return len(d.lines)
+}
+
+// findKey returns the index of the line that assigns key in the table whose header
+// is at index header, or -1. Lines whose value continues on later lines are not
+// returned since they cannot be edited on their own.
+func (d *tomlDoc) findKey(header int, key string) int {
+	for i := header + 1; i < d.tableEnd(header); i++ {
+		k, ok := parseKeyLine(d.lines[i])
+		if !ok || k != key {
+			continue
+		}
+
+		var m map[string]interface{}
+		if _, err := toml.Decode(d.lines[i], &m); err != nil {
+			return -1
+		}
+
+		return i
+	}
+
+	return -1
+}
+
+func (d *tomlDoc) deleteKey(header int, key string) bool {
+	i := d.findKey(header, key)
+	if i < 0 {
+		return false
+	}
+
+	d.lines = append(d.lines[:i], d.lines[i+1:]...)
+
+	return true
+}
+
+// setKey replaces the line that assigns key or, if the key is new, inserts a line
+// after the last assignment in the table.
+func (d *tomlDoc) setKey(header int, key string, v interface{}, existed bool) bool {
+	value, err := tomlValue(v)
+	if err != nil {
+		return false
+	}
+
+	if existed {
+		i := d.findKey(header, key)
+		if i < 0 {
+			return false
+		}
+
+		line := d.lines[i]
+		indent := line[:len(line)-len(strings.TrimLeft(line, " \t"))]
+		d.lines[i] = fmt.Sprintf("%s%s = %s\n", indent, tomlKey(key), value)
+
+		return true
+	}
+
+	at := header + 1
+	for i := header + 1; i < d.tableEnd(header); i++ {
+		if _, ok := parseKeyLine(d.lines[i]); ok {
+			at = i + 1
+		}
+	}
+
+	if at > 0 && !strings.HasSuffix(d.lines[at-1], "\n") {
+		d.lines[at-1] += "\n"
+	}
+
+	line := fmt.Sprintf("%s = %s\n", tomlKey(key), value)
+	d.lines = append(d.lines[:at], append([]string{line}, d.lines[at:]...)...)
+
+	return true
+}
+
+// appendTable adds a new table holding values at the end of the document.
+func (d *tomlDoc) appendTable(values Env, path ...string) bool {
+	if n := len(d.lines); n > 0 && !strings.HasSuffix(d.lines[n-1], "\n") {
+		d.lines[n-1] += "\n"
+	}
+	if len(d.lines) > 0 {
+		d.lines = append(d.lines, "\n")
+	}
+
+	keys := make([]string, len(path))
+	for i := range path {
+		keys[i] = tomlKey(path[i])
+	}
+	d.lines = append(d.lines, fmt.Sprintf("[%s]\n", strings.Join(keys, ".")))
+
+	for _, key := range sortedKeys(values) {
+		value, err := tomlValue(values[key])
+		if err != nil {
+			return false
+		}
+		d.lines = append(d.lines, fmt.Sprintf("%s = %s\n", tomlKey(key), value))
+	}
+
+	return true
+}
+
+// parseTableHeader returns the keys of a [table] header line.
+func parseTableHeader(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if !strings.HasPrefix(line, "[") || strings.HasPrefix(line, "[[") {
+		return nil, false
+	}
+
+	end := strings.LastIndex(line, "]")
+	if end < 0 {
+		return nil, false
+	}
+
+	keys, n, ok := parseDottedKey(line[1:end])
+	if !ok || strings.TrimSpace(line[1+n:end]) != "" {
+		return nil, false
+	}
+
+	return keys, true
+}
+
+// parseKeyPath returns the keys of a key = value line, which are more than one for a
+// dotted key such as client.timeout.
+func parseKeyPath(line string) ([]string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == '[' {
+		return nil, false
+	}
+
+	keys, n, ok := parseDottedKey(line)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(line[n:]), "=") {
+		return nil, false
+	}
+
+	return keys, true
+}
+
+// parseDottedKey parses a key made of bare or quoted keys joined by dots at the start
+// of s and returns its keys along with the number of bytes it took up.
+func parseDottedKey(s string) ([]string, int, bool) {
+	var keys []string
+	n := 0
+	for {
+		n += len(s[n:]) - len(strings.TrimLeft(s[n:], " \t"))
+
+		key, m, ok := parseKey(s[n:])
+		if !ok {
+			return nil, 0, false
+		}
+		keys = append(keys, key)
+		n += m
+
+		rest := strings.TrimLeft(s[n:], " \t")
+		if !strings.HasPrefix(rest, ".") {
+			return keys, n, true
+		}
+		n += len(s[n:]) - len(rest) + 1
+	}
+}
+
+// parseKeyLine returns the key of a key = value line. Dotted keys are returned as
+// written.
+func parseKeyLine(line string) (string, bool) {
+	line = strings.TrimSpace(line)
+	if line == "" || line[0] == '#' || line[0] == '[' {
+		return "", false
+	}
+
+	key, n, ok := parseKey(line)
+	if !ok || !strings.HasPrefix(strings.TrimSpace(line[n:]), "=") {
+		return "", false
+	}
+
+	return key, true
+}
+
+var bareKey = regexp.MustCompile(`^[A-Za-z0-9_-]+`)
+
+// parseKey parses a bare or quoted key at the start of s and returns it along with
+// the number of bytes it took up.
+func parseKey(s string) (string, int, bool) {
+	switch {
+	case strings.HasPrefix(s, `"`):
+		for i := 1; i < len(s); i++ {
+			if s[i] == '\\' {
+				i++
+			} else if s[i] == '"' {
+				key, err := strconv.Unquote(s[:i+1])
+				return key, i + 1, err == nil
+			}
+		}
+		return "", 0, false
+	case strings.HasPrefix(s, "'"):
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", 0, false
+		}
+		return s[1 : end+1], end + 2, true
+	}
+
+	key := bareKey.FindString(s)
+	return key, len(key), key != ""
+}
+
+// tomlKey returns key as a bare key if possible and as a quoted key otherwise.
+func tomlKey(key string) string {
+	if key != "" && bareKey.FindString(key) == key {
+		return key
+	}
+
+	return tomlString(key)
+}
+
+// tomlString returns s as a TOML basic string.
+func tomlString(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for _, r := range s {
+		switch r {
+		case '"':
+			b.WriteString(`\"`)
+		case '\\':
+			b.WriteString(`\\`)
+		case '\n':
+			b.WriteString(`\n`)
+		case '\t':
+			b.WriteString(`\t`)
+		case '\r':
+			b.WriteString(`\r`)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&b, `\u%04X`, r)
+			} else {
+				b.WriteRune(r)
+			}
+		}
+	}
+	b.WriteByte('"')
+
+	return b.String()
+}
+
+// tomlValue formats an env value as an inline TOML value.
+func tomlValue(v interface{}) (string, error) {
+	switch v := v.(type) {
+	case string:
+		return tomlString(v), nil
+	case bool:
+		return strconv.FormatBool(v), nil
+	case int64:
+		return strconv.FormatInt(v, 10), nil
+	case int:
+		return strconv.Itoa(v), nil
+	case float64:
+		s := strconv.FormatFloat(v, 'g', -1, 64)
+		if !strings.ContainsAny(s, ".eEn") {
+			s += ".0"
+		}
+		return s, nil
+	case time.Time:
+		return v.Format(time.RFC3339Nano), nil
+	case []interface{}:
+		items := make([]string, len(v))
+		for i := range v {
+			item, err := tomlValue(v[i])
+			if err != nil {
+				return "", err
+			}
+			items[i] = item
+		}
+		return "[" + strings.Join(items, ", ") + "]", nil
+	case map[string]interface{}:
+		items := make([]string, 0, len(v))
+		for _, k := range sortedKeys(v) {
+			item, err := tomlValue(v[k])
+			if err != nil {
+				return "", err
+			}
+			items = append(items, tomlKey(k)+" = "+item)
+		}
+		return "{ " + strings.Join(items, ", ") + " }", nil
+	}
+
+	return "", fmt.Errorf("unsupported value %T", v)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
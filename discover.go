@@ -0,0 +1,118 @@
+package reql
+
+import (
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// ReqfileExt is the file extension of reqfiles.
+const ReqfileExt = ".hcl"
+
+// FindReqfiles returns the paths of every reqfile under root in lexical order.
+// Directories whose names begin with a dot are not searched.
+func FindReqfiles(root string) ([]string, error) {
+	var files []string
+	err := filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if d.IsDir() {
+			if path != root && strings.HasPrefix(d.Name(), ".") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		if filepath.Ext(path) == ReqfileExt {
+			files = append(files, path)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// Glob returns the paths of the files matching pattern. In addition to the syntax of
+// filepath.Match, a path segment of ** matches zero or more directories, so
+// requests/**/*.hcl matches every reqfile under requests. Patterns without ** are
+// handed to filepath.Glob.
+func Glob(pattern string) ([]string, error) {
+	if !strings.Contains(pattern, "**") {
+		return filepath.Glob(pattern)
+	}
+
+	segments := strings.Split(filepath.ToSlash(filepath.Clean(pattern)), "/")
+	for _, segment := range segments {
+		if _, err := path.Match(segment, ""); err != nil {
+			return nil, err
+		}
+	}
+
+	// Walk from the longest leading directory without meta characters.
+	static := 0
+	for static < len(segments) && !strings.ContainsAny(segments[static], `*?[\`) {
+		static++
+	}
+
+	root := "."
+	if static > 0 {
+		root = filepath.FromSlash(strings.Join(segments[:static], "/"))
+		if root == "" {
+			root = string(filepath.Separator)
+		}
+	}
+
+	var files []string
+	err := filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+		if err != nil {
+			if os.IsNotExist(err) && p == root {
+				return filepath.SkipDir
+			}
+			return err
+		}
+
+		if !d.IsDir() && matchSegments(segments, strings.Split(filepath.ToSlash(p), "/")) {
+			files = append(files, p)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// matchSegments reports whether every segment of name matches the corresponding
+// segment of pattern, where a ** segment matches any number of name segments.
+func matchSegments(pattern, name []string) bool {
+	for len(pattern) > 0 {
+		if pattern[0] == "**" {
+			for i := 0; i <= len(name); i++ {
+				if matchSegments(pattern[1:], name[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+
+		if len(name) == 0 {
+			return false
+		} else if ok, _ := path.Match(pattern[0], name[0]); !ok {
+			return false
+		}
+
+		pattern, name = pattern[1:], name[1:]
+	}
+
+	return len(name) == 0
+}
@@ -0,0 +1,126 @@
+package reql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+// writeTree creates an empty file for every name under a temporary directory.
+func writeTree(t *testing.T, names ...string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, nil, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestFindReqfiles(t *testing.T) {
+	dir := writeTree(t,
+		"b.hcl",
+		"a.hcl",
+		"notes.txt",
+		"users/create.hcl",
+		"users/admin/delete.hcl",
+		".git/config.hcl",
+	)
+
+	got, err := FindReqfiles(dir)
+	if err != nil {
+		t.Fatalf("FindReqfiles() error = %v", err)
+	}
+
+	want := []string{
+		filepath.Join(dir, "a.hcl"),
+		filepath.Join(dir, "b.hcl"),
+		filepath.Join(dir, "users/admin/delete.hcl"),
+		filepath.Join(dir, "users/create.hcl"),
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("FindReqfiles() = %v, want %v", got, want)
+	}
+
+	if _, err := FindReqfiles(filepath.Join(dir, "missing")); err == nil {
+		t.Error("FindReqfiles() error = nil, want an error for a missing root")
+	}
+}
+
+func TestGlob(t *testing.T) {
+	dir := writeTree(t,
+		"ping.hcl",
+		"users/create.hcl",
+		"users/notes.txt",
+		"users/admin/delete.hcl",
+		"orders/list.hcl",
+	)
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    []string
+		wantErr bool
+	}{
+		{
+			name:    "Without doublestar",
+			pattern: "users/*.hcl",
+			want:    []string{"users/create.hcl"},
+		},
+		{
+			name:    "Every reqfile",
+			pattern: "**/*.hcl",
+			want:    []string{"orders/list.hcl", "ping.hcl", "users/admin/delete.hcl", "users/create.hcl"},
+		},
+		{
+			name:    "Under a directory",
+			pattern: "users/**/*.hcl",
+			want:    []string{"users/admin/delete.hcl", "users/create.hcl"},
+		},
+		{
+			name:    "Doublestar in the middle",
+			pattern: "**/admin/*",
+			want:    []string{"users/admin/delete.hcl"},
+		},
+		{
+			name:    "Trailing doublestar",
+			pattern: "users/**",
+			want:    []string{"users/admin/delete.hcl", "users/create.hcl", "users/notes.txt"},
+		},
+		{
+			name:    "Missing directory",
+			pattern: "missing/**/*.hcl",
+			want:    nil,
+		},
+		{
+			name:    "Bad pattern",
+			pattern: "**/[.hcl",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := Glob(filepath.Join(dir, tt.pattern))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Glob() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+
+			var want []string
+			for _, name := range tt.want {
+				want = append(want, filepath.Join(dir, name))
+			}
+			if !reflect.DeepEqual(got, want) {
+				t.Errorf("Glob() = %v, want %v", got, want)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package reql
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// ReadDotenv reads the dotenv file at path. See ParseDotenv for the format.
+func ReadDotenv(path string) (map[string]string, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	values, err := ParseDotenv(f)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%w", path, err)
+	}
+
+	return values, nil
+}
+
+// ParseDotenv parses KEY=VALUE lines. Blank lines and lines starting with # are
+// ignored, and keys may be preceded by export. Values may be wrapped in single
+// quotes, which are taken literally, or double quotes, which support the \n, \t,
+// \", and \\ escapes. Unquoted values end at a # preceded by whitespace and are
+// trimmed. Later lines override earlier ones.
+func ParseDotenv(r io.Reader) (map[string]string, error) {
+	values := make(map[string]string)
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		parts := strings.SplitN(strings.TrimPrefix(line, "export "), "=", 2)
+		key := strings.TrimSpace(parts[0])
+		if len(parts) != 2 || key == "" || strings.ContainsAny(key, " \t") {
+			return nil, fmt.Errorf("%d: expected KEY=VALUE", n)
+		}
+
+		value, err := parseDotenvValue(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, fmt.Errorf("%d: %s: %w", n, key, err)
+		}
+
+		values[key] = value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+
+	return values, nil
+}
+
+func parseDotenvValue(s string) (string, error) {
+	if s == "" {
+		return "", nil
+	}
+
+	switch quote := s[0]; quote {
+	case '\'':
+		end := strings.IndexByte(s[1:], '\'')
+		if end < 0 {
+			return "", fmt.Errorf("unterminated quoted value")
+		}
+		return s[1 : end+1], nil
+	case '"':
+		var b strings.Builder
+		for i := 1; i < len(s); i++ {
+			switch c := s[i]; {
+			case c == '"':
+				return b.String(), nil
+			case c == '\\' && i+1 < len(s):
+				i++
+				switch s[i] {
+				case 'n':
+					b.WriteByte('\n')
+				case 't':
+					b.WriteByte('\t')
+				default:
+					b.WriteByte(s[i])
+				}
+			default:
+				b.WriteByte(c)
+			}
+		}
+		return "", fmt.Errorf("unterminated quoted value")
+	}
+
+	if i := strings.Index(s, " #"); i >= 0 {
+		s = s[:i]
+	} else if i := strings.Index(s, "\t#"); i >= 0 {
+		s = s[:i]
+	}
+
+	return strings.TrimSpace(s), nil
+}
@@ -0,0 +1,59 @@
+package reql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestParseDotenv(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    map[string]string
+		wantErr bool
+	}{
+		{
+			name: "Plain values",
+			src:  "# comment\n\nTOKEN=abc\nexport USER = admin \n",
+			want: map[string]string{"TOKEN": "abc", "USER": "admin"},
+		},
+		{
+			name: "Inline comments",
+			src:  "A=1 # one\nB=x#y\n",
+			want: map[string]string{"A": "1", "B": "x#y"},
+		},
+		{
+			name: "Quoted values",
+			src:  "A='a \\n # b'\nB=\"line\\nnext \\\"q\\\"\" # c\nC=\n",
+			want: map[string]string{"A": "a \\n # b", "B": "line\nnext \"q\"", "C": ""},
+		},
+		{
+			name: "Later values override earlier ones",
+			src:  "A=1\nA=2\n",
+			want: map[string]string{"A": "2"},
+		},
+		{
+			name:    "Missing separator",
+			src:     "A\n",
+			wantErr: true,
+		},
+		{
+			name:    "Unterminated quote",
+			src:     "A=\"abc\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseDotenv(strings.NewReader(tt.src))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("ParseDotenv() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("ParseDotenv() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,247 @@
+package reql
+
+import (
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
+	ctyjson "github.com/zclconf/go-cty/cty/json"
+)
+
+// newEvalContext builds the context reqfile templates are evaluated in. The same
+// context is later extended with the exchange variables to evaluate assertions and
+// captures. Besides env and vars, the process environment is exposed as os. The file
+// function reads paths relative to dir.
+func newEvalContext(env Env, vars *Vars, dir string) *hcl.EvalContext {
+	return &hcl.EvalContext{
+		Variables: map[string]cty.Value{
+			"env":  env.object(),
+			"vars": vars.object(),
+			"os":   stringMapVal(environ()),
+		},
+		Functions: functions(dir),
+	}
+}
+
+// environ returns the environment variables of the process.
+func environ() map[string]string {
+	m := make(map[string]string)
+	for _, kv := range os.Environ() {
+		if i := strings.Index(kv, "="); i > 0 {
+			m[kv[:i]] = kv[i+1:]
+		}
+	}
+
+	return m
+}
+
+// exchangeContext returns a child of ctx that exposes the request as req and the
+// response as res.
+//
+// res is an object with the attributes code, status, proto, headers, body, json,
+// time_ms, and timing. req is an object with the attributes method, url, headers, and body.
+// Header maps contain every header under both its canonical and lowercase name.
+// res.json holds the body decoded as JSON, or null if the body is not valid JSON.
+// res.time_ms is the total time in milliseconds and res.timing breaks it down into
+// dns_ms, connect_ms, tls_ms, ttfb_ms, and total_ms.
+func exchangeContext(ctx *hcl.EvalContext, ex *Exchange) *hcl.EvalContext {
+	child := ctx.NewChild()
+	child.Variables = map[string]cty.Value{
+		"req": cty.ObjectVal(map[string]cty.Value{
+			"method":  cty.StringVal(ex.HTTPRequest.Method),
+			"url":     cty.StringVal(ex.HTTPRequest.URL.String()),
+			"headers": headerMapVal(ex.HTTPRequest.Header),
+			"body":    cty.StringVal(ex.Request.Body),
+		}),
+		"res": cty.ObjectVal(map[string]cty.Value{
+			"code":    cty.NumberIntVal(int64(ex.Response.StatusCode)),
+			"status":  cty.StringVal(ex.Response.Status),
+			"proto":   cty.StringVal(ex.Response.Proto),
+			"headers": headerMapVal(ex.Response.Header),
+			"body":    cty.StringVal(string(ex.Body)),
+			"json":    ex.JSON(),
+			"time_ms": millisVal(ex.Timing.Total),
+			"timing": cty.ObjectVal(map[string]cty.Value{
+				"dns_ms":     millisVal(ex.Timing.DNS),
+				"connect_ms": millisVal(ex.Timing.Connect),
+				"tls_ms":     millisVal(ex.Timing.TLSHandshake),
+				"ttfb_ms":    millisVal(ex.Timing.TimeToFirstByte),
+				"total_ms":   millisVal(ex.Timing.Total),
+			}),
+		}),
+	}
+
+	return child
+}
+
+// millisVal returns d in milliseconds as a cty number.
+func millisVal(d time.Duration) cty.Value {
+	return cty.NumberFloatVal(Millis(d))
+}
+
+// jsonVal decodes b as JSON. Objects become cty objects and arrays become tuples so
+// that values of mixed types can be traversed with attribute and index syntax.
+func jsonVal(b []byte) cty.Value {
+	ty, err := ctyjson.ImpliedType(b)
+	if err != nil {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+
+	v, err := ctyjson.Unmarshal(b, ty)
+	if err != nil {
+		return cty.NullVal(cty.DynamicPseudoType)
+	}
+
+	return v
+}
+
+func stringMapVal(m map[string]string) cty.Value {
+	if len(m) == 0 {
+		return cty.MapValEmpty(cty.String)
+	}
+
+	vals := make(map[string]cty.Value, len(m))
+	for k, v := range m {
+		vals[k] = cty.StringVal(v)
+	}
+
+	return cty.MapVal(vals)
+}
+
+// object converts the env to a cty object. It is safe to call on a nil Env.
+func (e Env) object() cty.Value {
+	return goCtyValue(map[string]interface{}(e))
+}
+
+// goCtyValue converts a value decoded from TOML to the matching cty value. Arrays
+// become tuples since TOML arrays may mix types, and tables become objects. Values
+// of other types are converted to strings.
+func goCtyValue(v interface{}) cty.Value {
+	switch v := v.(type) {
+	case nil:
+		return cty.NullVal(cty.DynamicPseudoType)
+	case string:
+		return cty.StringVal(v)
+	case bool:
+		return cty.BoolVal(v)
+	case int64:
+		return cty.NumberIntVal(v)
+	case int:
+		return cty.NumberIntVal(int64(v))
+	case float64:
+		return cty.NumberFloatVal(v)
+	case time.Time:
+		return cty.StringVal(v.Format(time.RFC3339Nano))
+	case []interface{}:
+		if len(v) == 0 {
+			return cty.EmptyTupleVal
+		}
+
+		vals := make([]cty.Value, len(v))
+		for i := range v {
+			vals[i] = goCtyValue(v[i])
+		}
+		return cty.TupleVal(vals)
+	case []map[string]interface{}:
+		vals := make([]interface{}, len(v))
+		for i := range v {
+			vals[i] = v[i]
+		}
+		return goCtyValue(vals)
+	case map[string]interface{}:
+		if len(v) == 0 {
+			return cty.EmptyObjectVal
+		}
+
+		vals := make(map[string]cty.Value, len(v))
+		for k := range v {
+			vals[k] = goCtyValue(v[k])
+		}
+		return cty.ObjectVal(vals)
+	}
+
+	return cty.StringVal(fmt.Sprint(v))
+}
+
+func headerMapVal(h map[string][]string) cty.Value {
+	m := make(map[string]string, 2*len(h))
+	for k, v := range h {
+		joined := strings.Join(v, ", ")
+		m[k] = joined
+		m[strings.ToLower(k)] = joined
+	}
+
+	return stringMapVal(m)
+}
+
+// lookupVariable finds name in ctx or the closest ancestor that defines it.
+func lookupVariable(ctx *hcl.EvalContext, name string) (cty.Value, bool) {
+	for ; ctx != nil; ctx = ctx.Parent() {
+		if v, ok := ctx.Variables[name]; ok {
+			return v, true
+		}
+	}
+
+	return cty.NilVal, false
+}
+
+// contextResolver resolves expression paths against the variables in ctx. Missing
+// map keys resolve to null so that absent headers can be compared.
+func contextResolver(ctx *hcl.EvalContext) Resolver {
+	return func(path []string) (interface{}, error) {
+		v, ok := lookupVariable(ctx, path[0])
+		if !ok {
+			return nil, fmt.Errorf("unknown variable %q", path[0])
+		}
+
+		for i, seg := range path[1:] {
+			switch {
+			case v.IsNull():
+				return nil, nil
+			case v.Type().IsObjectType() && v.Type().HasAttribute(seg):
+				v = v.GetAttr(seg)
+			case v.Type().IsMapType():
+				key := cty.StringVal(seg)
+				if v.HasIndex(key).False() {
+					return nil, nil
+				}
+				v = v.Index(key)
+			case v.Type().IsListType() || v.Type().IsTupleType():
+				n, err := strconv.Atoi(seg)
+				if err != nil || v.HasIndex(cty.NumberIntVal(int64(n))).False() {
+					return nil, fmt.Errorf("invalid index %q for %s", seg, strings.Join(path[:i+1], "."))
+				}
+				v = v.Index(cty.NumberIntVal(int64(n)))
+			default:
+				return nil, fmt.Errorf("unknown property %q", strings.Join(path[:i+2], "."))
+			}
+		}
+
+		return goValue(v, strings.Join(path, "."))
+	}
+}
+
+// goValue converts a primitive cty value into the representation used by Expr.
+func goValue(v cty.Value, name string) (interface{}, error) {
+	if v.IsNull() {
+		return nil, nil
+	} else if !v.IsKnown() {
+		return nil, fmt.Errorf("%s is not known", name)
+	}
+
+	switch v.Type() {
+	case cty.String:
+		return v.AsString(), nil
+	case cty.Number:
+		f, _ := v.AsBigFloat().Float64()
+		return f, nil
+	case cty.Bool:
+		return v.True(), nil
+	}
+
+	return nil, fmt.Errorf("%s is a %s, not a primitive value", name, v.Type().FriendlyName())
+}
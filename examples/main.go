@@ -5,8 +5,13 @@ import (
 	"io"
 	"net/http"
 	"os"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/h2c"
 )
 
+const token = "s3cr3t"
+
 func main() {
 	http.HandleFunc("/ping", func(w http.ResponseWriter, r *http.Request) {
 		if r.Method != http.MethodGet {
@@ -27,8 +32,31 @@ func main() {
 		w.Write(b)
 	})
 
+	http.HandleFunc("/login", func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodPost {
+			http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"access_token": "` + token + `", "token_type": "Bearer"}`))
+	})
+
+	http.HandleFunc("/whoami", func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("Authorization") != "Bearer "+token {
+			http.Error(w, "unauthorized", http.StatusUnauthorized)
+			return
+		}
+
+		w.Header().Add("Content-Type", "application/json; charset=utf-8")
+		w.Write([]byte(`{"user": "example"}`))
+	})
+
+	// Accept HTTP/2 over cleartext so that reqfiles can set http_version = "h2c".
+	server := &http.Server{Addr: ":8080", Handler: h2c.NewHandler(http.DefaultServeMux, &http2.Server{})}
+
 	fmt.Println("Server listening on :8080...")
-	err := http.ListenAndServe(":8080", nil)
+	err := server.ListenAndServe()
 	if err != nil {
 		fmt.Println(err)
 		os.Exit(1)
@@ -13,19 +13,15 @@ request {
 
 response {
     assert "Status code" {
-        expr = "res.code == 200"
+        expr = res.code == 200
     }
     assert "Content-Type header" {
-        expr = "res.headers.Content-Type == application/json; charset=utf-8"
+        expr = res.headers.Content-Type == "application/json; charset=utf-8"
     }
     assert "Content-Length header" {
-        expr = "res.headers.Content-Length > 0"
+        expr = res.headers.Content-Length > 0
     }
     assert "Body" {
-        expr = <<-BODY
-            res.body == {
-                "foo": "bar"
-            }
-        BODY
+        expr = res.json.foo == "bar" && length(res.json) == 1
     }
-}
\ No newline at end of file
+}
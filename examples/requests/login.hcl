@@ -0,0 +1,17 @@
+request {
+  method = "POST"
+  url = "${env.base_url}/login"
+}
+
+response {
+    assert "Status code" {
+      expr = res.code == 200
+    }
+    assert "Token" {
+      expr = typeof(res.json.access_token) == "string"
+    }
+}
+
+capture "token" {
+    from = res.json.access_token
+}
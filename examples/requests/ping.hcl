@@ -5,15 +5,15 @@ request {
 
 response {
     assert "Status code" {
-      expr = "res.code == 200"
+      expr = res.code == 200
     }
     assert "Conent-Type Header" {
-      expr = "res.headers.Content-Type == text/plain"
+      expr = res.headers.Content-Type == "text/plain"
     }
     assert "Content-Length Header" {
-      expr = "res.headers.content-length > 0"
+      expr = res.headers.content-length > 0
     }
     assert "Body" {
-      expr = "res.body == pong"
+      expr = res.body == "pong"
     }
-}
\ No newline at end of file
+}
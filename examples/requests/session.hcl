@@ -0,0 +1,30 @@
+request "login" {
+  method = "POST"
+  url = "${env.base_url}/login"
+
+  response {
+    assert "Status code" {
+      expr = res.code == 200
+    }
+  }
+
+  capture "token" {
+    from = res.json.access_token
+  }
+}
+
+request "whoami" {
+  depends_on = ["#login"]
+
+  method = "GET"
+  url = "${env.base_url}/whoami"
+  headers = {
+    Authorization = "Bearer ${vars.token}"
+  }
+
+  response {
+    assert "User" {
+      expr = res.json.user == "example"
+    }
+  }
+}
@@ -0,0 +1,18 @@
+depends_on = ["login"]
+
+request {
+  method = "GET"
+  url = "${env.base_url}/whoami"
+  headers = {
+    Authorization = "Bearer ${vars.token}"
+  }
+}
+
+response {
+    assert "Status code" {
+      expr = res.code == 200
+    }
+    assert "User" {
+      expr = res.json.user == "example"
+    }
+}
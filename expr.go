@@ -0,0 +1,627 @@
+package reql
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// Expr is a parsed assertion expression. Expressions are built from number, string,
+// and boolean literals, variable paths such as res.headers.Content-Type or
+// res.json.items[0].id, the comparison operators ==, !=, <, <=, >, and >=, the
+// logical operators &&, ||, and !, and parentheses for grouping.
+//
+// Deprecated: Expr only evaluates assertions written as quoted strings, which are
+// kept for backwards compatibility and get no new features. Assertions should be
+// written as native HCL expressions.
+type Expr struct {
+	src  string
+	root exprNode
+}
+
+// ExprError describes a problem found while parsing or evaluating an expression. The
+// line and column are 1-based and point into the expression source.
+type ExprError struct {
+	Line   int
+	Column int
+	Msg    string
+}
+
+func (e *ExprError) Error() string {
+	return fmt.Sprintf("%d:%d: %s", e.Line, e.Column, e.Msg)
+}
+
+// Resolver looks up the value of a variable path. Index steps such as [0] or ["id"]
+// appear in the path as their plain key, so res.json.items[0] is passed as
+// ["res", "json", "items", "0"]. Resolved values must be float64, string, bool,
+// or nil.
+type Resolver func(path []string) (interface{}, error)
+
+// ParseExpr parses src into an expression. An *ExprError is returned if src is not
+// a valid expression.
+func ParseExpr(src string) (*Expr, error) {
+	tokens, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &exprParser{src: src, tokens: tokens}
+	root, err := p.parseOr()
+	if err != nil {
+		return nil, err
+	}
+
+	if tok := p.peek(); tok.kind != tokenEOF {
+		return nil, p.errorf(tok.pos, "unexpected %s", tok)
+	}
+
+	return &Expr{src: src, root: root}, nil
+}
+
+// Eval evaluates the expression using resolve to look up variables.
+func (e *Expr) Eval(resolve Resolver) (interface{}, error) {
+	return e.root.eval(&exprEnv{src: e.src, resolve: resolve})
+}
+
+// String returns the source the expression was parsed from.
+func (e *Expr) String() string {
+	return e.src
+}
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenIdent
+	tokenNumber
+	tokenString
+	tokenTrue
+	tokenFalse
+	tokenNull
+	tokenDot
+	tokenLBrack
+	tokenRBrack
+	tokenLParen
+	tokenRParen
+	tokenNot
+	tokenAnd
+	tokenOr
+	tokenEq
+	tokenNeq
+	tokenLt
+	tokenLte
+	tokenGt
+	tokenGte
+)
+
+var tokenSymbols = map[tokenKind]string{
+	tokenDot:    ".",
+	tokenLBrack: "[",
+	tokenRBrack: "]",
+	tokenLParen: "(",
+	tokenRParen: ")",
+	tokenNot:    "!",
+	tokenAnd:    "&&",
+	tokenOr:     "||",
+	tokenEq:     "==",
+	tokenNeq:    "!=",
+	tokenLt:     "<",
+	tokenLte:    "<=",
+	tokenGt:     ">",
+	tokenGte:    ">=",
+}
+
+type token struct {
+	kind tokenKind
+	pos  int
+	text string
+}
+
+func (t token) String() string {
+	switch t.kind {
+	case tokenEOF:
+		return "end of expression"
+	case tokenIdent:
+		return fmt.Sprintf("identifier %q", t.text)
+	case tokenNumber:
+		return fmt.Sprintf("number %s", t.text)
+	case tokenString:
+		return fmt.Sprintf("string %q", t.text)
+	case tokenTrue, tokenFalse, tokenNull:
+		return t.text
+	}
+
+	return fmt.Sprintf("%q", tokenSymbols[t.kind])
+}
+
+// twoCharOperators maps operators that may be followed by '=' or repeated.
+var twoCharOperators = map[string]tokenKind{
+	"==": tokenEq,
+	"!=": tokenNeq,
+	"<=": tokenLte,
+	">=": tokenGte,
+	"&&": tokenAnd,
+	"||": tokenOr,
+}
+
+var oneCharOperators = map[byte]tokenKind{
+	'.': tokenDot,
+	'[': tokenLBrack,
+	']': tokenRBrack,
+	'(': tokenLParen,
+	')': tokenRParen,
+	'!': tokenNot,
+	'<': tokenLt,
+	'>': tokenGt,
+}
+
+func lex(src string) ([]token, error) {
+	var tokens []token
+
+	for i := 0; i < len(src); {
+		r, size := utf8.DecodeRuneInString(src[i:])
+		switch {
+		case unicode.IsSpace(r):
+			i += size
+		case r == '"' || r == '\'':
+			text, end, err := lexString(src, i)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, token{kind: tokenString, pos: i, text: text})
+			i = end
+		case isDigit(r) || (r == '-' && i+1 < len(src) && isDigit(rune(src[i+1]))):
+			end := i + 1
+			for end < len(src) && (isDigit(rune(src[end])) || src[end] == '.') {
+				end++
+			}
+			text := src[i:end]
+			if _, err := strconv.ParseFloat(text, 64); err != nil {
+				return nil, newExprError(src, i, "invalid number %q", text)
+			}
+			tokens = append(tokens, token{kind: tokenNumber, pos: i, text: text})
+			i = end
+		case isIdentStart(r):
+			end := i + size
+			for end < len(src) {
+				r, size := utf8.DecodeRuneInString(src[end:])
+				if !isIdentPart(r) {
+					break
+				}
+				end += size
+			}
+			text := src[i:end]
+			kind := tokenIdent
+			switch text {
+			case "true":
+				kind = tokenTrue
+			case "false":
+				kind = tokenFalse
+			case "null":
+				kind = tokenNull
+			}
+			tokens = append(tokens, token{kind: kind, pos: i, text: text})
+			i = end
+		default:
+			if i+2 <= len(src) {
+				if kind, ok := twoCharOperators[src[i:i+2]]; ok {
+					tokens = append(tokens, token{kind: kind, pos: i, text: src[i : i+2]})
+					i += 2
+					continue
+				}
+			}
+			if kind, ok := oneCharOperators[src[i]]; ok {
+				tokens = append(tokens, token{kind: kind, pos: i, text: src[i : i+1]})
+				i++
+				continue
+			}
+			return nil, newExprError(src, i, "unexpected character %q", r)
+		}
+	}
+
+	return append(tokens, token{kind: tokenEOF, pos: len(src)}), nil
+}
+
+// lexString reads a quoted string starting at src[start] and returns its unescaped
+// contents along with the offset just past the closing quote.
+func lexString(src string, start int) (string, int, error) {
+	quote := src[start]
+
+	var b strings.Builder
+	for i := start + 1; i < len(src); i++ {
+		switch c := src[i]; c {
+		case quote:
+			return b.String(), i + 1, nil
+		case '\\':
+			if i+1 >= len(src) {
+				return "", 0, newExprError(src, i, "unterminated escape sequence")
+			}
+			i++
+			switch src[i] {
+			case 'n':
+				b.WriteByte('\n')
+			case 't':
+				b.WriteByte('\t')
+			case '\\', '"', '\'':
+				b.WriteByte(src[i])
+			default:
+				return "", 0, newExprError(src, i-1, "unknown escape sequence \\%c", src[i])
+			}
+		default:
+			b.WriteByte(c)
+		}
+	}
+
+	return "", 0, newExprError(src, start, "unterminated string")
+}
+
+func isDigit(r rune) bool {
+	return r >= '0' && r <= '9'
+}
+
+func isIdentStart(r rune) bool {
+	return r == '_' || unicode.IsLetter(r)
+}
+
+// isIdentPart allows dashes so that header names such as Content-Type can be used
+// as path segments.
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || unicode.IsDigit(r) || r == '-'
+}
+
+func newExprError(src string, pos int, format string, args ...interface{}) *ExprError {
+	line, col := 1, 1
+	for _, r := range src[:pos] {
+		if r == '\n' {
+			line++
+			col = 1
+		} else {
+			col++
+		}
+	}
+
+	return &ExprError{Line: line, Column: col, Msg: fmt.Sprintf(format, args...)}
+}
+
+type exprParser struct {
+	src    string
+	tokens []token
+	pos    int
+}
+
+func (p *exprParser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *exprParser) next() token {
+	tok := p.tokens[p.pos]
+	if tok.kind != tokenEOF {
+		p.pos++
+	}
+	return tok
+}
+
+func (p *exprParser) errorf(pos int, format string, args ...interface{}) error {
+	return newExprError(p.src, pos, format, args...)
+}
+
+func (p *exprParser) parseOr() (exprNode, error) {
+	left, err := p.parseAnd()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenOr {
+		op := p.next()
+		right, err := p.parseAnd()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: op.pos, op: op.kind, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseAnd() (exprNode, error) {
+	left, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+
+	for p.peek().kind == tokenAnd {
+		op := p.next()
+		right, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		left = &logicalNode{pos: op.pos, op: op.kind, left: left, right: right}
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseComparison() (exprNode, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	switch p.peek().kind {
+	case tokenEq, tokenNeq, tokenLt, tokenLte, tokenGt, tokenGte:
+		op := p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &comparisonNode{pos: op.pos, op: op.kind, left: left, right: right}, nil
+	}
+
+	return left, nil
+}
+
+func (p *exprParser) parseUnary() (exprNode, error) {
+	if p.peek().kind == tokenNot {
+		op := p.next()
+		operand, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &notNode{pos: op.pos, operand: operand}, nil
+	}
+
+	return p.parsePrimary()
+}
+
+func (p *exprParser) parsePrimary() (exprNode, error) {
+	tok := p.next()
+	switch tok.kind {
+	case tokenNumber:
+		n, _ := strconv.ParseFloat(tok.text, 64)
+		return &literalNode{value: n}, nil
+	case tokenString:
+		return &literalNode{value: tok.text}, nil
+	case tokenTrue:
+		return &literalNode{value: true}, nil
+	case tokenFalse:
+		return &literalNode{value: false}, nil
+	case tokenNull:
+		return &literalNode{value: nil}, nil
+	case tokenIdent:
+		path := []string{tok.text}
+		for {
+			switch p.peek().kind {
+			case tokenDot:
+				p.next()
+				seg := p.next()
+				if seg.kind != tokenIdent {
+					return nil, p.errorf(seg.pos, "expected identifier after \".\", found %s", seg)
+				}
+				path = append(path, seg.text)
+				continue
+			case tokenLBrack:
+				p.next()
+				key := p.next()
+				if key.kind != tokenNumber && key.kind != tokenString {
+					return nil, p.errorf(key.pos, "expected index or key after \"[\", found %s", key)
+				}
+				if closing := p.next(); closing.kind != tokenRBrack {
+					return nil, p.errorf(closing.pos, "expected \"]\", found %s", closing)
+				}
+				path = append(path, key.text)
+				continue
+			}
+			break
+		}
+		return &pathNode{pos: tok.pos, path: path}, nil
+	case tokenLParen:
+		inner, err := p.parseOr()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRParen {
+			return nil, p.errorf(closing.pos, "expected \")\", found %s", closing)
+		}
+		return inner, nil
+	}
+
+	return nil, p.errorf(tok.pos, "unexpected %s", tok)
+}
+
+type exprEnv struct {
+	src     string
+	resolve Resolver
+}
+
+func (env *exprEnv) errorf(pos int, format string, args ...interface{}) error {
+	return newExprError(env.src, pos, format, args...)
+}
+
+type exprNode interface {
+	eval(env *exprEnv) (interface{}, error)
+}
+
+type literalNode struct {
+	value interface{}
+}
+
+func (n *literalNode) eval(env *exprEnv) (interface{}, error) {
+	return n.value, nil
+}
+
+type pathNode struct {
+	pos  int
+	path []string
+}
+
+func (n *pathNode) eval(env *exprEnv) (interface{}, error) {
+	v, err := env.resolve(n.path)
+	if err != nil {
+		return nil, env.errorf(n.pos, "%v", err)
+	}
+
+	return v, nil
+}
+
+type notNode struct {
+	pos     int
+	operand exprNode
+}
+
+func (n *notNode) eval(env *exprEnv) (interface{}, error) {
+	v, err := n.operand.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return nil, env.errorf(n.pos, "operator ! requires a boolean operand, got %s", typeName(v))
+	}
+
+	return !b, nil
+}
+
+type logicalNode struct {
+	pos         int
+	op          tokenKind
+	left, right exprNode
+}
+
+func (n *logicalNode) eval(env *exprEnv) (interface{}, error) {
+	left, err := n.operand(env, n.left)
+	if err != nil {
+		return nil, err
+	}
+
+	if (n.op == tokenAnd && !left) || (n.op == tokenOr && left) {
+		return left, nil
+	}
+
+	return n.operand(env, n.right)
+}
+
+func (n *logicalNode) operand(env *exprEnv, node exprNode) (bool, error) {
+	v, err := node.eval(env)
+	if err != nil {
+		return false, err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return false, env.errorf(n.pos, "operator %s requires boolean operands, got %s", tokenSymbols[n.op], typeName(v))
+	}
+
+	return b, nil
+}
+
+type comparisonNode struct {
+	pos         int
+	op          tokenKind
+	left, right exprNode
+}
+
+func (n *comparisonNode) eval(env *exprEnv) (interface{}, error) {
+	left, err := n.left.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	right, err := n.right.eval(env)
+	if err != nil {
+		return nil, err
+	}
+
+	left, right = coerce(left, right)
+
+	switch n.op {
+	case tokenEq:
+		return left == right, nil
+	case tokenNeq:
+		return left != right, nil
+	}
+
+	var cmp int
+	switch l := left.(type) {
+	case float64:
+		r, ok := right.(float64)
+		if !ok {
+			return nil, n.mismatch(env, left, right)
+		}
+		cmp = compareFloats(l, r)
+	case string:
+		r, ok := right.(string)
+		if !ok {
+			return nil, n.mismatch(env, left, right)
+		}
+		cmp = strings.Compare(l, r)
+	default:
+		return nil, n.mismatch(env, left, right)
+	}
+
+	switch n.op {
+	case tokenLt:
+		return cmp < 0, nil
+	case tokenLte:
+		return cmp <= 0, nil
+	case tokenGt:
+		return cmp > 0, nil
+	default:
+		return cmp >= 0, nil
+	}
+}
+
+func (n *comparisonNode) mismatch(env *exprEnv, left, right interface{}) error {
+	return env.errorf(n.pos, "cannot compare %s %s %s", typeName(left), tokenSymbols[n.op], typeName(right))
+}
+
+// coerce converts a string operand to a number when the other operand is a number.
+// Response properties such as header values are always strings, so this allows
+// expressions like res.headers.Content-Length > 0 to compare numerically.
+func coerce(left, right interface{}) (interface{}, interface{}) {
+	if s, ok := left.(string); ok {
+		if _, ok := right.(float64); ok {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return n, right
+			}
+		}
+	}
+
+	if s, ok := right.(string); ok {
+		if _, ok := left.(float64); ok {
+			if n, err := strconv.ParseFloat(strings.TrimSpace(s), 64); err == nil {
+				return left, n
+			}
+		}
+	}
+
+	return left, right
+}
+
+func compareFloats(l, r float64) int {
+	switch {
+	case l < r:
+		return -1
+	case l > r:
+		return 1
+	}
+
+	return 0
+}
+
+func typeName(v interface{}) string {
+	switch v.(type) {
+	case nil:
+		return "null"
+	case float64:
+		return "number"
+	case string:
+		return "string"
+	case bool:
+		return "bool"
+	}
+
+	return fmt.Sprintf("%T", v)
+}
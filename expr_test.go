@@ -0,0 +1,161 @@
+package reql
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+)
+
+func testResolver(path []string) (interface{}, error) {
+	vars := map[string]interface{}{
+		"res.code":                   float64(200),
+		"res.body":                   "pong",
+		"res.headers.Content-Length": "4",
+		"res.ok":                     true,
+	}
+
+	key := ""
+	for i, p := range path {
+		if i > 0 {
+			key += "."
+		}
+		key += p
+	}
+
+	v, ok := vars[key]
+	if !ok {
+		return nil, fmt.Errorf("unknown property %q", key)
+	}
+
+	return v, nil
+}
+
+func TestExpr_Eval(t *testing.T) {
+	tests := []struct {
+		name    string
+		src     string
+		want    interface{}
+		wantErr bool
+	}{
+		{
+			name: "Number equality",
+			src:  "res.code == 200",
+			want: true,
+		},
+		{
+			name: "Numeric comparison coerces strings",
+			src:  "res.headers.Content-Length > 10",
+			want: false,
+		},
+		{
+			name: "Quoted string literal",
+			src:  `res.body == "pong"`,
+			want: true,
+		},
+		{
+			name: "Single quoted string with escapes",
+			src:  `'a\'b' == "a'b"`,
+			want: true,
+		},
+		{
+			name: "Logical operators and grouping",
+			src:  "!(res.code != 200) && (res.body == 'ping' || res.ok)",
+			want: true,
+		},
+		{
+			name: "Negative numbers",
+			src:  "-1.5 < 0",
+			want: true,
+		},
+		{
+			name: "Null comparison",
+			src:  "null == null",
+			want: true,
+		},
+		{
+			name:    "Non-boolean logical operand",
+			src:     "res.code && true",
+			wantErr: true,
+		},
+		{
+			name:    "Mismatched comparison",
+			src:     "true < 1",
+			wantErr: true,
+		},
+		{
+			name:    "Unknown property",
+			src:     "res.foo == 1",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, err := ParseExpr(tt.src)
+			if err != nil {
+				t.Fatalf("ParseExpr() unexpected error = %v", err)
+			}
+
+			got, err := expr.Eval(testResolver)
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Expr.Eval() error = %v, wantErr %v", err, tt.wantErr)
+				return
+			}
+			if got != tt.want {
+				t.Errorf("Expr.Eval() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseExprReportsPositions(t *testing.T) {
+	tests := []struct {
+		name     string
+		src      string
+		wantLine int
+		wantCol  int
+	}{
+		{
+			name:     "Unexpected token",
+			src:      "res.code == == 200",
+			wantLine: 1,
+			wantCol:  13,
+		},
+		{
+			name:     "Unterminated string",
+			src:      "res.body == 'pong",
+			wantLine: 1,
+			wantCol:  13,
+		},
+		{
+			name:     "Unexpected character",
+			src:      "res.code ==\n  200 # 1",
+			wantLine: 2,
+			wantCol:  7,
+		},
+		{
+			name:     "Missing closing paren",
+			src:      "(res.code == 200",
+			wantLine: 1,
+			wantCol:  17,
+		},
+		{
+			name:     "Trailing tokens",
+			src:      "res.code 200",
+			wantLine: 1,
+			wantCol:  10,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := ParseExpr(tt.src)
+
+			var exprErr *ExprError
+			if !errors.As(err, &exprErr) {
+				t.Fatalf("ParseExpr() error = %v, want *ExprError", err)
+			}
+			if exprErr.Line != tt.wantLine || exprErr.Column != tt.wantCol {
+				t.Errorf("ParseExpr() error at %d:%d, want %d:%d", exprErr.Line, exprErr.Column, tt.wantLine, tt.wantCol)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+package reql
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"math/big"
+	"net/url"
+	"os"
+	"path/filepath"
+	"time"
+	"unicode/utf8"
+
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/function"
+	"github.com/zclconf/go-cty/cty/function/stdlib"
+	"github.com/zclconf/go-cty/cty/gocty"
+)
+
+// timeNow returns the current time. Tests replace it to get stable timestamps.
+var timeNow = time.Now
+
+// functions returns the functions available to reqfile expressions: most of the
+// go-cty standard library under the names Terraform uses, plus helpers for building
+// requests. file reads paths relative to dir.
+func functions(dir string) map[string]function.Function {
+	return map[string]function.Function{
+		// Numbers
+		"abs":      stdlib.AbsoluteFunc,
+		"ceil":     stdlib.CeilFunc,
+		"floor":    stdlib.FloorFunc,
+		"log":      stdlib.LogFunc,
+		"max":      stdlib.MaxFunc,
+		"min":      stdlib.MinFunc,
+		"parseint": stdlib.ParseIntFunc,
+		"pow":      stdlib.PowFunc,
+		"signum":   stdlib.SignumFunc,
+
+		// Strings
+		"chomp":      stdlib.ChompFunc,
+		"format":     stdlib.FormatFunc,
+		"formatlist": stdlib.FormatListFunc,
+		"indent":     stdlib.IndentFunc,
+		"join":       stdlib.JoinFunc,
+		"lower":      stdlib.LowerFunc,
+		"regex":      stdlib.RegexFunc,
+		"regexall":   stdlib.RegexAllFunc,
+		"replace":    stdlib.ReplaceFunc,
+		"split":      stdlib.SplitFunc,
+		"strrev":     stdlib.ReverseFunc,
+		"substr":     stdlib.SubstrFunc,
+		"title":      stdlib.TitleFunc,
+		"trim":       stdlib.TrimFunc,
+		"trimprefix": stdlib.TrimPrefixFunc,
+		"trimspace":  stdlib.TrimSpaceFunc,
+		"trimsuffix": stdlib.TrimSuffixFunc,
+		"upper":      stdlib.UpperFunc,
+
+		// Collections
+		"chunklist":    stdlib.ChunklistFunc,
+		"coalesce":     stdlib.CoalesceFunc,
+		"coalescelist": stdlib.CoalesceListFunc,
+		"compact":      stdlib.CompactFunc,
+		"concat":       stdlib.ConcatFunc,
+		"contains":     stdlib.ContainsFunc,
+		"distinct":     stdlib.DistinctFunc,
+		"element":      stdlib.ElementFunc,
+		"flatten":      stdlib.FlattenFunc,
+		"keys":         stdlib.KeysFunc,
+		"length":       lengthFunc,
+		"lookup":       stdlib.LookupFunc,
+		"merge":        stdlib.MergeFunc,
+		"range":        stdlib.RangeFunc,
+		"reverse":      stdlib.ReverseListFunc,
+		"slice":        stdlib.SliceFunc,
+		"sort":         stdlib.SortFunc,
+		"values":       stdlib.ValuesFunc,
+		"zipmap":       stdlib.ZipmapFunc,
+
+		// Encoding
+		"base64decode": base64DecodeFunc,
+		"base64encode": base64EncodeFunc,
+		"csvdecode":    stdlib.CSVDecodeFunc,
+		"jsondecode":   stdlib.JSONDecodeFunc,
+		"jsonencode":   stdlib.JSONEncodeFunc,
+		"urlencode":    urlEncodeFunc,
+
+		// Hashing
+		"hmac_sha256": hmacSHA256Func,
+		"sha256":      sha256Func,
+
+		// Time
+		"formatdate": stdlib.FormatDateFunc,
+		"now":        nowFunc,
+		"timeadd":    stdlib.TimeAddFunc,
+		"timestamp":  timestampFunc,
+
+		// Miscellaneous
+		"file":       fileFunc(dir),
+		"random_int": randomIntFunc,
+		"typeof":     typeOfFunc,
+		"uuid":       uuidFunc,
+	}
+}
+
+// lengthFunc returns the number of elements in a collection, the number of
+// attributes in an object, or the number of characters in a string.
+var lengthFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		v := args[0]
+		ty := v.Type()
+		switch {
+		case ty == cty.String:
+			return stdlib.Strlen(v)
+		case ty.IsObjectType():
+			return cty.NumberIntVal(int64(len(ty.AttributeTypes()))), nil
+		case ty.IsCollectionType() || ty.IsTupleType():
+			return v.Length(), nil
+		}
+
+		return cty.UnknownVal(cty.Number), fmt.Errorf("cannot take the length of %s", ty.FriendlyName())
+	},
+})
+
+// typeOfFunc returns the JSON type name of its argument: string, number, bool,
+// object, array, or null.
+var typeOfFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{
+			Name:             "value",
+			Type:             cty.DynamicPseudoType,
+			AllowNull:        true,
+			AllowDynamicType: true,
+		},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		v := args[0]
+		ty := v.Type()
+		switch {
+		case v.IsNull():
+			return cty.StringVal("null"), nil
+		case ty == cty.String:
+			return cty.StringVal("string"), nil
+		case ty == cty.Number:
+			return cty.StringVal("number"), nil
+		case ty == cty.Bool:
+			return cty.StringVal("bool"), nil
+		case ty.IsObjectType() || ty.IsMapType():
+			return cty.StringVal("object"), nil
+		case ty.IsTupleType() || ty.IsListType() || ty.IsSetType():
+			return cty.StringVal("array"), nil
+		}
+
+		return cty.StringVal(ty.FriendlyName()), nil
+	},
+})
+
+// stringFunc returns a function of a single string argument.
+func stringFunc(name string, impl func(string) (string, error)) function.Function {
+	return function.New(&function.Spec{
+		Params: []function.Parameter{{Name: name, Type: cty.String}},
+		Type:   function.StaticReturnType(cty.String),
+		Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+			s, err := impl(args[0].AsString())
+			if err != nil {
+				return cty.UnknownVal(cty.String), err
+			}
+
+			return cty.StringVal(s), nil
+		},
+	})
+}
+
+// base64EncodeFunc encodes a string with standard base64.
+var base64EncodeFunc = stringFunc("str", func(s string) (string, error) {
+	return base64.StdEncoding.EncodeToString([]byte(s)), nil
+})
+
+// base64DecodeFunc decodes a standard base64 string. The result must be valid UTF-8.
+var base64DecodeFunc = stringFunc("str", func(s string) (string, error) {
+	b, err := base64.StdEncoding.DecodeString(s)
+	if err != nil {
+		return "", fmt.Errorf("invalid base64: %v", err)
+	} else if !utf8.Valid(b) {
+		return "", fmt.Errorf("decoded base64 is not valid UTF-8")
+	}
+
+	return string(b), nil
+})
+
+// urlEncodeFunc escapes a string for use in a URL query.
+var urlEncodeFunc = stringFunc("str", func(s string) (string, error) {
+	return url.QueryEscape(s), nil
+})
+
+// sha256Func returns the hex encoded SHA-256 digest of a string.
+var sha256Func = stringFunc("str", func(s string) (string, error) {
+	sum := sha256.Sum256([]byte(s))
+	return hex.EncodeToString(sum[:]), nil
+})
+
+// hmacSHA256Func returns the hex encoded HMAC-SHA256 of a message with a key.
+var hmacSHA256Func = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "key", Type: cty.String},
+		{Name: "message", Type: cty.String},
+	},
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		mac := hmac.New(sha256.New, []byte(args[0].AsString()))
+		mac.Write([]byte(args[1].AsString()))
+
+		return cty.StringVal(hex.EncodeToString(mac.Sum(nil))), nil
+	},
+})
+
+// uuidFunc returns a random version 4 UUID.
+var uuidFunc = function.New(&function.Spec{
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		var b [16]byte
+		if _, err := rand.Read(b[:]); err != nil {
+			return cty.UnknownVal(cty.String), err
+		}
+		b[6] = b[6]&0x0f | 0x40
+		b[8] = b[8]&0x3f | 0x80
+
+		return cty.StringVal(fmt.Sprintf("%x-%x-%x-%x-%x", b[0:4], b[4:6], b[6:8], b[8:10], b[10:])), nil
+	},
+})
+
+// nowFunc returns the current UTC time in RFC 3339 format.
+var nowFunc = function.New(&function.Spec{
+	Type: function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return cty.StringVal(timeNow().UTC().Format(time.RFC3339)), nil
+	},
+})
+
+// timestampFunc formats the current UTC time with the format syntax of formatdate,
+// e.g. timestamp("YYYY-MM-DD").
+var timestampFunc = function.New(&function.Spec{
+	Params: []function.Parameter{{Name: "format", Type: cty.String}},
+	Type:   function.StaticReturnType(cty.String),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		return stdlib.FormatDate(args[0], cty.StringVal(timeNow().UTC().Format(time.RFC3339Nano)))
+	},
+})
+
+// randomIntFunc returns a random integer in the inclusive range [min, max].
+var randomIntFunc = function.New(&function.Spec{
+	Params: []function.Parameter{
+		{Name: "min", Type: cty.Number},
+		{Name: "max", Type: cty.Number},
+	},
+	Type: function.StaticReturnType(cty.Number),
+	Impl: func(args []cty.Value, retType cty.Type) (cty.Value, error) {
+		var min, max int64
+		if err := gocty.FromCtyValue(args[0], &min); err != nil {
+			return cty.UnknownVal(cty.Number), function.NewArgError(0, err)
+		}
+		if err := gocty.FromCtyValue(args[1], &max); err != nil {
+			return cty.UnknownVal(cty.Number), function.NewArgError(1, err)
+		}
+		if min > max {
+			return cty.UnknownVal(cty.Number), fmt.Errorf("min %d is greater than max %d", min, max)
+		}
+
+		n := new(big.Int).Sub(big.NewInt(max), big.NewInt(min))
+		r, err := rand.Int(rand.Reader, n.Add(n, big.NewInt(1)))
+		if err != nil {
+			return cty.UnknownVal(cty.Number), err
+		}
+
+		return cty.NumberIntVal(r.Add(r, big.NewInt(min)).Int64()), nil
+	},
+})
+
+// fileFunc returns a function that reads a file as a string. Relative paths are
+// resolved against dir.
+func fileFunc(dir string) function.Function {
+	return stringFunc("path", func(path string) (string, error) {
+		if !filepath.IsAbs(path) {
+			path = filepath.Join(dir, path)
+		}
+
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return "", err
+		} else if !utf8.Valid(b) {
+			return "", fmt.Errorf("%s is not valid UTF-8", path)
+		}
+
+		return string(b), nil
+	})
+}
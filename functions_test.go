@@ -0,0 +1,143 @@
+package reql
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func TestFunctions(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "body.json"), []byte(`{"a":1}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	timeNow = func() time.Time { return time.Date(2021, 3, 4, 5, 6, 7, 0, time.FixedZone("", 3600)) }
+	defer func() { timeNow = time.Now }()
+
+	tests := []struct {
+		name    string
+		expr    string
+		want    cty.Value
+		wantErr bool
+	}{
+		{
+			name: "stdlib",
+			expr: `upper(format("%s-%d", "id", 7))`,
+			want: cty.StringVal("ID-7"),
+		},
+		{
+			name: "jsonencode",
+			expr: `jsonencode({a = [1, true]})`,
+			want: cty.StringVal(`{"a":[1,true]}`),
+		},
+		{
+			name: "base64 round trip",
+			expr: `base64decode(base64encode("user:pass"))`,
+			want: cty.StringVal("user:pass"),
+		},
+		{
+			name: "base64encode",
+			expr: `base64encode("user:pass")`,
+			want: cty.StringVal("dXNlcjpwYXNz"),
+		},
+		{
+			name:    "Invalid base64",
+			expr:    `base64decode("%%%")`,
+			wantErr: true,
+		},
+		{
+			name: "urlencode",
+			expr: `urlencode("a b&c")`,
+			want: cty.StringVal("a+b%26c"),
+		},
+		{
+			name: "sha256",
+			expr: `sha256("abc")`,
+			want: cty.StringVal("ba7816bf8f01cfea414140de5dae2223b00361a396177a9cb410ff61f20015ad"),
+		},
+		{
+			name: "hmac_sha256",
+			expr: `hmac_sha256("key", "The quick brown fox jumps over the lazy dog")`,
+			want: cty.StringVal("f7bc83f430538424b13298e6aa6fb143ef4d59a14946175997479dbc2d1a3cd8"),
+		},
+		{
+			name: "now",
+			expr: `now()`,
+			want: cty.StringVal("2021-03-04T04:06:07Z"),
+		},
+		{
+			name: "timestamp",
+			expr: `timestamp("YYYY-MM-DD hh:mm")`,
+			want: cty.StringVal("2021-03-04 04:06"),
+		},
+		{
+			name: "random_int with a single value",
+			expr: `random_int(3, 3)`,
+			want: cty.NumberIntVal(3),
+		},
+		{
+			name:    "random_int with min greater than max",
+			expr:    `random_int(3, 2)`,
+			wantErr: true,
+		},
+		{
+			name: "file",
+			expr: `jsondecode(file("body.json")).a`,
+			want: cty.NumberIntVal(1),
+		},
+		{
+			name:    "Missing file",
+			expr:    `file("missing.json")`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatal(diags)
+			}
+
+			got, diags := expr.Value(newEvalContext(nil, nil, dir))
+			if diags.HasErrors() != tt.wantErr {
+				t.Errorf("Value() error = %v, wantErr %v", diags, tt.wantErr)
+				return
+			}
+			if !tt.wantErr && !got.RawEquals(tt.want) {
+				t.Errorf("Value() = %#v, want %#v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFunctions_Random(t *testing.T) {
+	ctx := newEvalContext(nil, nil, "")
+
+	expr, _ := hclsyntax.ParseExpression([]byte(`uuid()`), "test.hcl", hcl.InitialPos)
+	v, diags := expr.Value(ctx)
+	if diags.HasErrors() {
+		t.Fatal(diags)
+	}
+	uuid := regexp.MustCompile(`^[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}$`)
+	if !uuid.MatchString(v.AsString()) {
+		t.Errorf("uuid() = %s, want a version 4 UUID", v.AsString())
+	}
+
+	expr, _ = hclsyntax.ParseExpression([]byte(`random_int(1, 6)`), "test.hcl", hcl.InitialPos)
+	for i := 0; i < 100; i++ {
+		v, diags := expr.Value(ctx)
+		if diags.HasErrors() {
+			t.Fatal(diags)
+		}
+		if n, _ := v.AsBigFloat().Int64(); n < 1 || n > 6 {
+			t.Fatalf("random_int(1, 6) = %d, want a value in [1, 6]", n)
+		}
+	}
+}
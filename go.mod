@@ -5,6 +5,7 @@ go 1.17
 require (
 	github.com/urfave/cli/v2 v2.3.0
 	github.com/zclconf/go-cty v1.8.0
+	golang.org/x/net v0.17.0
 )
 
 require (
@@ -12,7 +13,7 @@ require (
 	github.com/apparentlymart/go-textseg/v13 v13.0.0 // indirect
 	github.com/google/go-cmp v0.3.1 // indirect
 	github.com/mitchellh/go-wordwrap v0.0.0-20150314170334-ad45545899c7 // indirect
-	golang.org/x/text v0.3.5 // indirect
+	golang.org/x/text v0.13.0 // indirect
 )
 
 require (
@@ -6,6 +6,7 @@ import (
 	"io"
 	"os"
 	"strings"
+	"sync"
 )
 
 // Logger represents a standard level logging interface. Every method logs the provided
@@ -86,8 +87,10 @@ func (l Level) validate() error {
 // level will be printed.
 //
 // Every log message is treated as a single line. If there is no newline at the end of the
-// message, then one will be added.
+// message, then one will be added. A LevelLogger is safe for concurrent use; messages from
+// different goroutines are never interleaved.
 type LevelLogger struct {
+	mu    sync.Mutex
 	w     io.Writer
 	level Level
 }
@@ -112,44 +115,47 @@ func NewLevelLogger(level Level, out io.Writer) (*LevelLogger, error) {
 // Logs at the LevelDebug level.
 func (l *LevelLogger) Debug(format string, args ...interface{}) {
 	if l.level <= LevelDebug {
-		l.printPrefixTag(LevelDebug)
-		l.printMessage([]byte(fmt.Sprintf(format, args...)))
+		l.log(LevelDebug, format, args...)
 	}
 }
 
 // Logs at the LevelInfo level.
 func (l *LevelLogger) Info(format string, args ...interface{}) {
 	if l.level <= LevelInfo {
-		l.printPrefixTag(LevelInfo)
-		l.printMessage([]byte(fmt.Sprintf(format, args...)))
+		l.log(LevelInfo, format, args...)
 	}
 }
 
 // Logs at the LevelWarn level.
 func (l *LevelLogger) Warn(format string, args ...interface{}) {
 	if l.level <= LevelWarn {
-		l.printPrefixTag(LevelWarn)
-		l.printMessage([]byte(fmt.Sprintf(format, args...)))
+		l.log(LevelWarn, format, args...)
 	}
 }
 
 // Logs at the LevelError level.
 func (l *LevelLogger) Error(format string, args ...interface{}) {
 	if l.level <= LevelError {
-		l.printPrefixTag(LevelError)
-		l.printMessage([]byte(fmt.Sprintf(format, args...)))
+		l.log(LevelError, format, args...)
 	}
 }
 
 // Logs at the LevelFatal level then calls os.Exit(1).
 func (l *LevelLogger) Fatal(format string, args ...interface{}) {
 	if l.level <= LevelFatal {
-		l.printPrefixTag(LevelFatal)
-		l.printMessage([]byte(fmt.Sprintf(format, args...)))
+		l.log(LevelFatal, format, args...)
 		os.Exit(1)
 	}
 }
 
+func (l *LevelLogger) log(level Level, format string, args ...interface{}) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.printPrefixTag(level)
+	l.printMessage([]byte(fmt.Sprintf(format, args...)))
+}
+
 func (l *LevelLogger) printPrefixTag(level Level) {
 	l.w.Write([]byte(fmt.Sprintf("[%s]: ", level)))
 }
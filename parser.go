@@ -2,94 +2,223 @@ package reql
 
 import (
 	"fmt"
-	"io"
-	"net/http"
-	"strconv"
+	"os"
+	"path/filepath"
 	"strings"
 
 	"github.com/hashicorp/hcl/v2"
-	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/gohcl"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
 	"github.com/zclconf/go-cty/cty"
 )
 
-func ParseReqfile(path string, env map[string]string) (Reqfile, error) {
-	vars := map[string]cty.Value{"env": cty.MapVal(map[string]cty.Value{"": cty.StringVal("")})}
+// RequestRefSep separates the path of a reqfile from the name of one of its
+// requests, as in requests/users.hcl#create.
+const RequestRefSep = "#"
+
+// SplitRequestRef splits a reference to a request into the path of its reqfile and
+// the name of the request. name is empty when ref refers to the whole reqfile.
+//
+// Paths may contain RequestRefSep. The reference is only split at the last
+// separator when the part after it contains no path separator and ref itself does
+// not name an existing file, so dir#1/ping.hcl and an existing a#b.hcl are paths.
+func SplitRequestRef(ref string) (path, name string) {
+	i := strings.LastIndex(ref, RequestRefSep)
+	if i < 0 || strings.ContainsAny(ref[i+len(RequestRefSep):], `/`+string(filepath.Separator)) {
+		return ref, ""
+	}
 
-	if len(env) > 0 {
-		envMap := map[string]cty.Value{}
-		for k, v := range env {
-			envMap[k] = cty.StringVal(v)
-		}
+	if info, err := os.Stat(ref); err == nil && !info.IsDir() {
+		return ref, ""
+	}
+
+	return ref[:i], ref[i+len(RequestRefSep):]
+}
 
-		vars["env"] = cty.MapVal(envMap)
+// JoinRequestRef returns a reference to the request with the given name in the
+// reqfile at path. An empty name refers to the whole reqfile.
+func JoinRequestRef(path, name string) string {
+	if name == "" {
+		return path
 	}
 
-	var reqfile Reqfile
-	err := hclsimple.DecodeFile(
-		path,
-		&hcl.EvalContext{Variables: vars},
-		&reqfile,
-	)
+	return path + RequestRefSep + name
+}
+
+// ParseReqfile decodes the reqfile at ref, which is either a path or a reference to
+// a named request such as requests/users.hcl#create. A reqfile with named requests
+// can only be parsed by reference to one of them. Templates may refer to the values
+// in env through the env variable and to captured values in vars through the vars
+// variable. vars may be nil. Secrets in env are resolved from their store.
+func ParseReqfile(ref string, env Env, vars *Vars) (Reqfile, error) {
+	path, name := SplitRequestRef(ref)
+
+	env, err := env.resolveSecrets()
 	if err != nil {
 		return Reqfile{}, err
 	}
 
-	for i := range reqfile.Response.Assertions {
-		reqfile.Response.Assertions[i].fn = ParseAssertion(reqfile.Response.Assertions[i].Expr)
+	src, err := os.ReadFile(path)
+	if err != nil {
+		return Reqfile{}, err
+	}
+
+	file, diags := hclparse.NewParser().ParseHCL(src, path)
+	if diags.HasErrors() {
+		return Reqfile{}, diags
+	}
+
+	names, err := requestNames(file.Body)
+	if err != nil {
+		return Reqfile{}, err
+	}
+
+	ctx := newEvalContext(env, vars, filepath.Dir(path))
+
+	var reqfile Reqfile
+	switch {
+	case len(names) > 0:
+		reqfile, err = decodeNamedRequest(file.Body, ctx, path, name)
+		if err != nil {
+			return Reqfile{}, err
+		}
+	case name != "":
+		return Reqfile{}, fmt.Errorf("%s: no request named %q", path, name)
+	default:
+		diags = gohcl.DecodeBody(file.Body, ctx, &reqfile)
+		if diags.HasErrors() {
+			return Reqfile{}, diags
+		}
+	}
+
+	reqfile.ctx = ctx
+	if reqfile.Client != nil {
+		reqfile.Request.Client = reqfile.Client.resolvePaths(filepath.Dir(path))
+	}
+
+	for i, assertion := range reqfile.Response.Assertions {
+		reqfile.Response.Assertions[i].source = strings.TrimSpace(string(assertion.Expr.Range().SliceBytes(src)))
+
+		legacy, err := checkLegacyAssertion(assertion.Expr)
+		if err != nil {
+			return Reqfile{}, fmt.Errorf("%s: assertion %q: %w", ref, assertion.Name, err)
+		}
+		reqfile.Response.Assertions[i].legacy = legacy
 	}
 
 	return reqfile, nil
 }
 
-type AssertionFunc func(*http.Request, *http.Response) bool
+// namedReqfile is a reqfile with labelled request blocks. Each request has its own
+// response and capture blocks, while depends_on and the client block apply to every
+// request.
+type namedReqfile struct {
+	DependsOn []string       `hcl:"depends_on,optional"`
+	Client    *ClientOptions `hcl:"client,block"`
+	Requests  []namedRequest `hcl:"request,block"`
+}
+
+type namedRequest struct {
+	Name      string    `hcl:"name,label"`
+	DependsOn []string  `hcl:"depends_on,optional"`
+	Response  *Response `hcl:"response,block"`
+	Captures  []Capture `hcl:"capture,block"`
+	Remain    hcl.Body  `hcl:",remain"`
+}
+
+// decodeNamedRequest decodes the request called name into a Reqfile. Only the
+// selected request is evaluated.
+func decodeNamedRequest(body hcl.Body, ctx *hcl.EvalContext, path, name string) (Reqfile, error) {
+	var file namedReqfile
+	diags := gohcl.DecodeBody(body, ctx, &file)
+	if diags.HasErrors() {
+		return Reqfile{}, diags
+	}
+
+	if name == "" {
+		return Reqfile{}, fmt.Errorf("%s: reqfile has multiple requests, select one as %s", path, JoinRequestRef(path, "name"))
+	}
 
-func ParseAssertion(cond string) AssertionFunc {
-	return func(request *http.Request, response *http.Response) bool {
-		parts := strings.SplitN(cond, " ", 3)
-		var l, r string
-		if strings.HasPrefix(parts[0], "res") {
-			l = responseProperty(response, parts[0][(strings.Index(parts[0], ".")+1):])
-		} else {
-			panic("idk what to do")
+	for _, request := range file.Requests {
+		if request.Name != name {
+			continue
 		}
 
-		r = parts[2]
+		reqfile := Reqfile{
+			DependsOn: append(append([]string(nil), file.DependsOn...), request.DependsOn...),
+			Client:    file.Client,
+			Captures:  request.Captures,
+		}
+		if request.Response != nil {
+			reqfile.Response = *request.Response
+		}
 
-		fmt.Printf("Asserting %s %s %s\n", l, parts[1], r)
+		diags = gohcl.DecodeBody(request.Remain, ctx, &reqfile.Request)
+		if diags.HasErrors() {
+			return Reqfile{}, diags
+		}
 
-		return getComparator(parts[1])(l, r)
+		return reqfile, nil
 	}
+
+	return Reqfile{}, fmt.Errorf("%s: no request named %q", path, name)
 }
 
-func responseProperty(res *http.Response, property string) string {
-	switch {
-	case property == "code":
-		return strconv.Itoa(res.StatusCode)
-	case strings.HasPrefix(property, "headers"):
-		return res.Header.Get(property[(strings.Index(property, ".") + 1):])
-	case property == "body":
-		b, _ := io.ReadAll(res.Body)
-		return string(b)
+// RequestNames returns the names of the labelled request blocks in the reqfile at
+// path in the order they are declared. It returns nil for a reqfile with a single
+// unlabelled request.
+func RequestNames(path string) ([]string, error) {
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
 	}
 
-	return ""
+	return requestNames(file.Body)
 }
 
-func getComparator(s string) func(string, string) bool {
-	switch s {
-	case "==":
-		return func(s1, s2 string) bool { return s1 == s2 }
-	case "!=":
-		return func(s1, s2 string) bool { return s1 != s2 }
-	case ">":
-		return func(s1, s2 string) bool { return s1 > s2 }
-	case ">=":
-		return func(s1, s2 string) bool { return s1 >= s2 }
-	case "<":
-		return func(s1, s2 string) bool { return s1 < s2 }
-	case "<=":
-		return func(s1, s2 string) bool { return s1 <= s2 }
-	default:
-		panic("unknown comparator")
+// requestNames reads the labels of the request blocks in body without decoding it,
+// which is not possible through a schema since the label is optional.
+func requestNames(body hcl.Body) ([]string, error) {
+	syntaxBody, ok := body.(*hclsyntax.Body)
+	if !ok {
+		return nil, nil
+	}
+
+	var names []string
+	seen := make(map[string]bool)
+	for _, block := range syntaxBody.Blocks {
+		if block.Type != "request" || len(block.Labels) == 0 {
+			continue
+		}
+
+		name := block.Labels[0]
+		if seen[name] {
+			return nil, fmt.Errorf("%s: duplicate request %q", block.DefRange(), name)
+		} else if strings.Contains(name, RequestRefSep) {
+			return nil, fmt.Errorf("%s: request name %q must not contain %q", block.DefRange(), name, RequestRefSep)
+		}
+
+		seen[name] = true
+		names = append(names, name)
 	}
+
+	return names, nil
+}
+
+// checkLegacyAssertion reports whether an assertion is written as a quoted
+// expression string rather than a native HCL expression, and any syntax error in
+// the string. Only strings that can be evaluated without a response are checked.
+func checkLegacyAssertion(expr hcl.Expression) (bool, error) {
+	if len(expr.Variables()) > 0 {
+		return false, nil
+	}
+
+	v, diags := expr.Value(nil)
+	if diags.HasErrors() || v.IsNull() || !v.IsKnown() || !v.Type().Equals(cty.String) {
+		return false, nil
+	}
+
+	_, err := ParseExpr(strings.TrimSpace(v.AsString()))
+	return true, err
 }
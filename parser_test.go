@@ -0,0 +1,259 @@
+package reql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+const namedReqfileSrc = `
+depends_on = ["login.hcl"]
+
+request "create" {
+  method = "POST"
+  url    = "${env.base_url}/users"
+
+  response {
+    assert "Created" {
+      expr = res.code == 201
+    }
+  }
+
+  capture "id" {
+    from = res.json.id
+  }
+}
+
+request "get" {
+  depends_on = ["#create"]
+
+  method = "GET"
+  url    = "${env.base_url}/users/${vars.id}"
+}
+`
+
+func writeReqfile(t *testing.T, src string) string {
+	t.Helper()
+
+	path := filepath.Join(t.TempDir(), "users.hcl")
+	if err := os.WriteFile(path, []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	return path
+}
+
+func TestParseReqfile_NamedRequests(t *testing.T) {
+	path := writeReqfile(t, namedReqfileSrc)
+	env := Env{"base_url": "http://localhost"}
+
+	vars := NewVars()
+	vars.Set("id", cty.StringVal("42"))
+
+	tests := []struct {
+		name       string
+		ref        string
+		wantURL    string
+		wantDeps   []string
+		assertions int
+		captures   int
+		wantErr    string
+	}{
+		{
+			name:       "First request",
+			ref:        path + "#create",
+			wantURL:    "http://localhost/users",
+			wantDeps:   []string{"login.hcl"},
+			assertions: 1,
+			captures:   1,
+		},
+		{
+			name:     "Second request",
+			ref:      path + "#get",
+			wantURL:  "http://localhost/users/42",
+			wantDeps: []string{"login.hcl", "#create"},
+		},
+		{
+			name:    "Unknown request",
+			ref:     path + "#delete",
+			wantErr: `no request named "delete"`,
+		},
+		{
+			name:    "No request selected",
+			ref:     path,
+			wantErr: "multiple requests",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reqfile, err := ParseReqfile(tt.ref, env, vars)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("ParseReqfile() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("ParseReqfile() unexpected error = %v", err)
+			}
+
+			if reqfile.Request.URL != tt.wantURL {
+				t.Errorf("ParseReqfile() URL = %v, want %v", reqfile.Request.URL, tt.wantURL)
+			}
+			if !reflect.DeepEqual(reqfile.DependsOn, tt.wantDeps) {
+				t.Errorf("ParseReqfile() DependsOn = %v, want %v", reqfile.DependsOn, tt.wantDeps)
+			}
+			if len(reqfile.Response.Assertions) != tt.assertions || len(reqfile.Captures) != tt.captures {
+				t.Errorf(
+					"ParseReqfile() got %d assertions and %d captures, want %d and %d",
+					len(reqfile.Response.Assertions), len(reqfile.Captures), tt.assertions, tt.captures,
+				)
+			}
+		})
+	}
+}
+
+func TestParseReqfile_NamedRequestsDependsOn(t *testing.T) {
+	path := writeReqfile(t, `
+depends_on = ["login.hcl", "setup.hcl"]
+
+request "create" {
+  depends_on = ["seed.hcl"]
+
+  method = "POST"
+  url    = "http://localhost/users"
+}
+
+request "get" {
+  depends_on = ["#create"]
+
+  method = "GET"
+  url    = "http://localhost/users/1"
+}
+`)
+
+	create, err := ParseReqfile(path+"#create", nil, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+	get, err := ParseReqfile(path+"#get", nil, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+
+	if want := []string{"login.hcl", "setup.hcl", "seed.hcl"}; !reflect.DeepEqual(create.DependsOn, want) {
+		t.Errorf("ParseReqfile() DependsOn = %v, want %v", create.DependsOn, want)
+	}
+	if want := []string{"login.hcl", "setup.hcl", "#create"}; !reflect.DeepEqual(get.DependsOn, want) {
+		t.Errorf("ParseReqfile() DependsOn = %v, want %v", get.DependsOn, want)
+	}
+}
+
+func TestParseReqfile_UnnamedRequest(t *testing.T) {
+	path := writeReqfile(t, "request {\n  method = \"GET\"\n  url = \"http://localhost\"\n}\nresponse {}\n")
+
+	if _, err := ParseReqfile(path, nil, nil); err != nil {
+		t.Errorf("ParseReqfile() unexpected error = %v", err)
+	}
+	if _, err := ParseReqfile(path+"#get", nil, nil); err == nil {
+		t.Error("ParseReqfile() error = nil, want an error for a named reference")
+	}
+}
+
+func TestReqfile_LegacyAssertions(t *testing.T) {
+	path := writeReqfile(t, `request {
+  method = "GET"
+  url = "http://localhost"
+}
+response {
+  assert "Native" {
+    expr = res.code == 200
+  }
+  assert "Legacy" {
+    expr = "res.code == 200"
+  }
+}
+`)
+
+	reqfile, err := ParseReqfile(path, nil, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+	if got := reqfile.LegacyAssertions(); !reflect.DeepEqual(got, []string{"Legacy"}) {
+		t.Errorf("Reqfile.LegacyAssertions() = %v, want [Legacy]", got)
+	}
+}
+
+func TestRequestNames(t *testing.T) {
+	got, err := RequestNames(writeReqfile(t, namedReqfileSrc))
+	if err != nil {
+		t.Fatalf("RequestNames() error = %v", err)
+	}
+	if want := []string{"create", "get"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("RequestNames() = %v, want %v", got, want)
+	}
+
+	_, err = RequestNames(writeReqfile(t, "request \"a\" {}\nrequest \"a\" {}\n"))
+	if err == nil || !strings.Contains(err.Error(), "duplicate request") {
+		t.Errorf("RequestNames() error = %v, want a duplicate request error", err)
+	}
+}
+
+func TestSplitRequestRef(t *testing.T) {
+	dir := t.TempDir()
+	hashFile := filepath.Join(dir, "a#b.hcl")
+	if err := os.WriteFile(hashFile, []byte("request {}\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		ref      string
+		wantPath string
+		wantName string
+	}{
+		{ref: "requests/users.hcl", wantPath: "requests/users.hcl"},
+		{ref: "requests/users.hcl#create", wantPath: "requests/users.hcl", wantName: "create"},
+		{ref: "users#create", wantPath: "users", wantName: "create"},
+		{ref: "#create", wantName: "create"},
+		{ref: "dir#1/ping.hcl", wantPath: "dir#1/ping.hcl"},
+		{ref: "dir#1/ping.hcl#create", wantPath: "dir#1/ping.hcl", wantName: "create"},
+		{ref: hashFile, wantPath: hashFile},
+		{ref: hashFile + "#create", wantPath: hashFile, wantName: "create"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.ref, func(t *testing.T) {
+			path, name := SplitRequestRef(tt.ref)
+			if path != tt.wantPath || name != tt.wantName {
+				t.Errorf("SplitRequestRef() = %q, %q, want %q, %q", path, name, tt.wantPath, tt.wantName)
+			}
+			if got := JoinRequestRef(path, name); got != tt.ref {
+				t.Errorf("JoinRequestRef() = %q, want %q", got, tt.ref)
+			}
+		})
+	}
+}
+
+func TestParseReqfile_OSVariables(t *testing.T) {
+	t.Setenv("REQL_TEST_TOKEN", "s3cr3t")
+	path := writeReqfile(t, `
+request {
+  method  = "GET"
+  url     = "http://localhost"
+  headers = {
+    Authorization = "Bearer ${os.REQL_TEST_TOKEN}"
+  }
+}
+response {}
+`)
+
+	reqfile, err := ParseReqfile(path, nil, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+	if got := reqfile.Request.Headers["Authorization"]; got != "Bearer s3cr3t" {
+		t.Errorf("ParseReqfile() Authorization = %q, want %q", got, "Bearer s3cr3t")
+	}
+}
@@ -0,0 +1,244 @@
+package reql
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclparse"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+	"github.com/zclconf/go-cty/cty/convert"
+)
+
+// Plan is an ordered list of reqfiles in which every reqfile comes after the
+// reqfiles it depends on.
+type Plan struct {
+	Steps []Step
+}
+
+// Step is a single request in a plan along with the paths of its direct
+// dependencies. Path is the path of the reqfile, or a reference such as
+// requests/users.hcl#create for a named request.
+type Step struct {
+	Path      string
+	DependsOn []string
+}
+
+// NewPlan orders files by their depends_on declarations. Files may also reference
+// named requests, and a reqfile with named requests is expanded into each of them in
+// declaration order. Dependencies are named by alias or by a path relative to the
+// declaring reqfile, optionally followed by #name to depend on a single named
+// request. #name alone refers to a request in the same reqfile. Dependencies that
+// are not in files are added to the plan. Otherwise files keep their relative order.
+// An error is returned if a dependency cannot be found or the dependencies form a
+// cycle.
+func NewPlan(files []string, aliases map[string]string) (*Plan, error) {
+	b := &planBuilder{
+		aliases: aliases,
+		deps:    make(map[string][]string),
+		state:   make(map[string]visitState),
+	}
+
+	for _, file := range files {
+		path, name := SplitRequestRef(file)
+
+		refs, err := ExpandRequestRef(JoinRequestRef(filepath.Clean(path), name))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, ref := range refs {
+			if err := b.visit(ref, nil); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return &Plan{Steps: b.steps}, nil
+}
+
+// ExpandRequestRef returns a reference to every named request of the reqfile at ref
+// when ref does not already name one. Otherwise ref is returned as is.
+func ExpandRequestRef(ref string) ([]string, error) {
+	path, name := SplitRequestRef(ref)
+	if name != "" {
+		return []string{ref}, nil
+	}
+
+	names, err := RequestNames(path)
+	if err != nil {
+		return nil, err
+	} else if len(names) == 0 {
+		return []string{ref}, nil
+	}
+
+	refs := make([]string, len(names))
+	for i, name := range names {
+		refs[i] = JoinRequestRef(path, name)
+	}
+
+	return refs, nil
+}
+
+type visitState int
+
+const (
+	unvisited visitState = iota
+	visiting
+	visited
+)
+
+type planBuilder struct {
+	aliases map[string]string
+	deps    map[string][]string
+	state   map[string]visitState
+	steps   []Step
+}
+
+// visit appends path to the plan after all of its dependencies using a depth first
+// search. stack holds the chain of reqfiles that led to path for cycle reporting.
+func (b *planBuilder) visit(path string, stack []string) error {
+	switch b.state[path] {
+	case visited:
+		return nil
+	case visiting:
+		cycle := append(stack[indexOf(stack, path):], path)
+		return fmt.Errorf("dependency cycle: %s", strings.Join(cycle, " -> "))
+	}
+
+	b.state[path] = visiting
+	stack = append(stack, path)
+
+	names, err := ReadDependencies(path)
+	if err != nil {
+		return err
+	}
+
+	var deps []string
+	for _, name := range names {
+		dep := b.resolve(path, name)
+		depPath, _ := SplitRequestRef(dep)
+		if _, err := os.Stat(depPath); err != nil {
+			return fmt.Errorf("%s: dependency %q: %w", path, name, err)
+		}
+
+		refs, err := ExpandRequestRef(dep)
+		if err != nil {
+			return err
+		}
+
+		for _, ref := range refs {
+			if err := b.visit(ref, stack); err != nil {
+				return err
+			}
+		}
+		deps = append(deps, refs...)
+	}
+
+	b.state[path] = visited
+	b.steps = append(b.steps, Step{Path: path, DependsOn: deps})
+
+	return nil
+}
+
+// resolve returns the reference to the dependency dep of the request at from.
+func (b *planBuilder) resolve(from, dep string) string {
+	path, name := SplitRequestRef(dep)
+	fromPath, _ := SplitRequestRef(from)
+
+	if path == "" {
+		path = fromPath
+	} else if alias, ok := b.aliases[path]; ok {
+		path = filepath.Clean(alias)
+	} else if filepath.IsAbs(path) {
+		path = filepath.Clean(path)
+	} else {
+		path = filepath.Join(filepath.Dir(fromPath), path)
+	}
+
+	return JoinRequestRef(path, name)
+}
+
+func indexOf(s []string, v string) int {
+	for i := range s {
+		if s[i] == v {
+			return i
+		}
+	}
+
+	return -1
+}
+
+// ReadDependencies returns the names listed in the depends_on attribute of the
+// reqfile at ref without evaluating the rest of the file. When ref names a request,
+// the names listed in the depends_on attribute of that request follow. The
+// attributes must be static lists of strings.
+func ReadDependencies(ref string) ([]string, error) {
+	path, name := SplitRequestRef(ref)
+
+	file, diags := hclparse.NewParser().ParseHCLFile(path)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	names, err := readDependsOn(file.Body)
+	if err != nil || name == "" {
+		return names, err
+	}
+
+	syntaxBody, ok := file.Body.(*hclsyntax.Body)
+	if !ok {
+		return names, nil
+	}
+
+	for _, block := range syntaxBody.Blocks {
+		if block.Type == "request" && len(block.Labels) > 0 && block.Labels[0] == name {
+			requestNames, err := readDependsOn(block.Body)
+			if err != nil {
+				return nil, err
+			}
+
+			return append(names, requestNames...), nil
+		}
+	}
+
+	return nil, fmt.Errorf("%s: no request named %q", path, name)
+}
+
+func readDependsOn(body hcl.Body) ([]string, error) {
+	content, _, diags := body.PartialContent(&hcl.BodySchema{
+		Attributes: []hcl.AttributeSchema{{Name: "depends_on"}},
+	})
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	attr, ok := content.Attributes["depends_on"]
+	if !ok {
+		return nil, nil
+	}
+
+	v, diags := attr.Expr.Value(nil)
+	if diags.HasErrors() {
+		return nil, diags
+	}
+
+	v, err := convert.Convert(v, cty.List(cty.String))
+	if err != nil || v.IsNull() || !v.IsWhollyKnown() {
+		return nil, fmt.Errorf("%s: depends_on must be a list of strings", attr.Range)
+	}
+
+	var names []string
+	for it := v.ElementIterator(); it.Next(); {
+		_, name := it.Element()
+		if name.IsNull() {
+			return nil, fmt.Errorf("%s: depends_on must not contain null", attr.Range)
+		}
+		names = append(names, name.AsString())
+	}
+
+	return names, nil
+}
@@ -0,0 +1,184 @@
+package reql
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func writeReqfiles(t *testing.T, deps map[string]string) string {
+	t.Helper()
+
+	dir := t.TempDir()
+	for name, dependsOn := range deps {
+		src := "request {\n  method = \"GET\"\n  url = \"http://localhost\"\n}\n"
+		if dependsOn != "" {
+			src = "depends_on = " + dependsOn + "\n" + src
+		}
+
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	return dir
+}
+
+func TestNewPlan(t *testing.T) {
+	tests := []struct {
+		name    string
+		deps    map[string]string
+		files   []string
+		aliases map[string]string
+		want    []string
+		wantErr string
+	}{
+		{
+			name:  "No dependencies keeps order",
+			deps:  map[string]string{"a.hcl": "", "b.hcl": ""},
+			files: []string{"b.hcl", "a.hcl"},
+			want:  []string{"b.hcl", "a.hcl"},
+		},
+		{
+			name:  "Dependencies come first",
+			deps:  map[string]string{"a.hcl": `["c.hcl"]`, "b.hcl": "", "c.hcl": `["./b.hcl"]`},
+			files: []string{"a.hcl", "b.hcl", "c.hcl"},
+			want:  []string{"b.hcl", "c.hcl", "a.hcl"},
+		},
+		{
+			name:    "Dependencies by alias are added",
+			deps:    map[string]string{"login.hcl": "", "me.hcl": `["login"]`},
+			files:   []string{"me.hcl"},
+			aliases: map[string]string{"login": "login.hcl"},
+			want:    []string{"login.hcl", "me.hcl"},
+		},
+		{
+			name:    "Cycles are reported",
+			deps:    map[string]string{"a.hcl": `["b.hcl"]`, "b.hcl": `["c.hcl"]`, "c.hcl": `["a.hcl"]`},
+			files:   []string{"a.hcl"},
+			wantErr: "dependency cycle",
+		},
+		{
+			name:    "Missing dependencies are reported",
+			deps:    map[string]string{"a.hcl": `["missing.hcl"]`},
+			files:   []string{"a.hcl"},
+			wantErr: "missing.hcl",
+		},
+		{
+			name:    "Dependencies must be strings",
+			deps:    map[string]string{"a.hcl": `"b.hcl"`},
+			files:   []string{"a.hcl"},
+			wantErr: "list of strings",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeReqfiles(t, tt.deps)
+
+			files := make([]string, len(tt.files))
+			for i, f := range tt.files {
+				files[i] = filepath.Join(dir, f)
+			}
+
+			aliases := make(map[string]string)
+			for k, v := range tt.aliases {
+				aliases[k] = filepath.Join(dir, v)
+			}
+
+			plan, err := NewPlan(files, aliases)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("NewPlan() error = %v, want error containing %q", err, tt.wantErr)
+				}
+				return
+			} else if err != nil {
+				t.Fatalf("NewPlan() unexpected error = %v", err)
+			}
+
+			var got []string
+			for _, step := range plan.Steps {
+				got = append(got, filepath.Base(step.Path))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPlan() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNewPlan_NamedRequests(t *testing.T) {
+	dir := t.TempDir()
+	files := map[string]string{
+		"login.hcl": "request {\n  method = \"POST\"\n  url = \"http://localhost\"\n}\n",
+		"users.hcl": `
+request "create" {
+  depends_on = ["login"]
+}
+
+request "get" {
+  depends_on = ["#create"]
+}
+
+request "delete" {
+  depends_on = ["#get"]
+}
+`,
+		"report.hcl": "depends_on = [\"users.hcl#delete\"]\nrequest {}\n",
+	}
+	for name, src := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	aliases := map[string]string{"login": filepath.Join(dir, "login.hcl")}
+
+	tests := []struct {
+		name  string
+		files []string
+		want  []string
+	}{
+		{
+			name:  "Reqfile is expanded into its requests",
+			files: []string{"users.hcl"},
+			want:  []string{"login.hcl", "users.hcl#create", "users.hcl#get", "users.hcl#delete"},
+		},
+		{
+			name:  "Single request pulls in its dependencies",
+			files: []string{"users.hcl#get"},
+			want:  []string{"login.hcl", "users.hcl#create", "users.hcl#get"},
+		},
+		{
+			name:  "Dependency on a named request",
+			files: []string{"report.hcl"},
+			want:  []string{"login.hcl", "users.hcl#create", "users.hcl#get", "users.hcl#delete", "report.hcl"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			paths := make([]string, len(tt.files))
+			for i, f := range tt.files {
+				paths[i] = filepath.Join(dir, f)
+			}
+
+			plan, err := NewPlan(paths, aliases)
+			if err != nil {
+				t.Fatalf("NewPlan() unexpected error = %v", err)
+			}
+
+			var got []string
+			for _, step := range plan.Steps {
+				got = append(got, filepath.Base(step.Path))
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("NewPlan() order = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	_, err := NewPlan([]string{filepath.Join(dir, "users.hcl#missing")}, aliases)
+	if err == nil || !strings.Contains(err.Error(), `no request named "missing"`) {
+		t.Errorf("NewPlan() error = %v, want an unknown request error", err)
+	}
+}
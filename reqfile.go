@@ -1,13 +1,64 @@
 package reql
 
 import (
+	"errors"
 	"fmt"
-	"net/http"
+	"strings"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/zclconf/go-cty/cty"
 )
 
 type Reqfile struct {
-	Request  Request  `hcl:"request,block"`
-	Response Response `hcl:"response,block"`
+	DependsOn []string       `hcl:"depends_on,optional"`
+	Client    *ClientOptions `hcl:"client,block"`
+	Request   Request        `hcl:"request,block"`
+	Response  Response       `hcl:"response,block"`
+	Captures  []Capture      `hcl:"capture,block"`
+
+	ctx *hcl.EvalContext
+}
+
+// Check evaluates every assertion against ex and returns one result per assertion.
+func (r Reqfile) Check(ex *Exchange) []AssertionResult {
+	ctx := exchangeContext(r.ctx, ex)
+
+	results := make([]AssertionResult, len(r.Response.Assertions))
+	for i, assertion := range r.Response.Assertions {
+		results[i] = AssertionResult{Name: assertion.Name, Err: assertion.Assert(ctx)}
+	}
+
+	return results
+}
+
+// LegacyAssertions returns the names of the assertions written as deprecated quoted
+// string expressions.
+func (r Reqfile) LegacyAssertions() []string {
+	var names []string
+	for _, assertion := range r.Response.Assertions {
+		if assertion.legacy {
+			names = append(names, assertion.Name)
+		}
+	}
+
+	return names
+}
+
+// Capture evaluates every capture against ex and stores the results in vars.
+// Evaluation stops at the first capture that fails.
+func (r Reqfile) Capture(ex *Exchange, vars *Vars) error {
+	ctx := exchangeContext(r.ctx, ex)
+
+	for _, capture := range r.Captures {
+		v, diags := capture.From.Value(ctx)
+		if diags.HasErrors() {
+			return fmt.Errorf("capture %q: %w", capture.Name, diags)
+		}
+
+		vars.Set(capture.Name, v)
+	}
+
+	return nil
 }
 
 type Headers struct {
@@ -15,13 +66,19 @@ type Headers struct {
 }
 
 type Request struct {
-	HTTPVersion string
+	// HTTPVersion selects the protocol, one of "1.1", "2", or "h2c". When empty,
+	// HTTP/2 is used if the server supports it over TLS.
+	HTTPVersion string `hcl:"http_version,optional"`
 	Method      string `hcl:"method"`
 	URL         string `hcl:"url"`
 
 	Headers map[string]string `hcl:"headers,optional"`
 
 	Body string `hcl:"body,optional"`
+
+	// Client overrides the client options for this request. It is set from the
+	// reqfile's client block.
+	Client *ClientOptions
 }
 
 func NewRequest() Request {
@@ -34,15 +91,67 @@ type Response struct {
 	Assertions []Assertion `hcl:"assert,block"`
 }
 
+// Capture is a named value taken from an exchange, such as an access token returned
+// by a login request. Captured values are exposed to later reqfiles as vars.<name>.
+type Capture struct {
+	Name string         `hcl:"name,label"`
+	From hcl.Expression `hcl:"from"`
+}
+
+// Assertion is a named condition about an exchange. Expr is usually a native HCL
+// expression such as res.code == 200. For backwards compatibility, an expression
+// written as a quoted string is parsed and evaluated with ParseExpr.
+//
+// Deprecated string expressions are frozen: they keep working but gain no new
+// features. Use a native HCL expression instead.
 type Assertion struct {
-	Name string `hcl:"name,label"`
-	Expr string `hcl:"expr"`
-	fn   AssertionFunc
+	Name string         `hcl:"name,label"`
+	Expr hcl.Expression `hcl:"expr"`
+
+	source string
+	// legacy is set when Expr is a quoted string expression.
+	legacy bool
 }
 
-func (a Assertion) Assert(request *http.Request, response *http.Response) error {
-	if !a.fn(request, response) {
-		return fmt.Errorf("%s failed assertion", a.Name)
+// Assert evaluates the assertion in ctx, which must define the res and req variables.
+func (a Assertion) Assert(ctx *hcl.EvalContext) error {
+	v, diags := a.Expr.Value(ctx)
+	if diags.HasErrors() {
+		return diags
+	}
+
+	if a.legacy && v.IsKnown() && !v.IsNull() && v.Type().Equals(cty.String) {
+		return a.assertLegacy(ctx, v.AsString())
+	}
+
+	if !v.Type().Equals(cty.Bool) {
+		return fmt.Errorf("assertion must evaluate to a bool, got %s", v.Type().FriendlyName())
+	} else if v.IsNull() || !v.IsKnown() {
+		return errors.New("assertion must evaluate to a bool, got null")
+	} else if v.False() {
+		return fmt.Errorf("expected %s", a.source)
+	}
+
+	return nil
+}
+
+func (a Assertion) assertLegacy(ctx *hcl.EvalContext, src string) error {
+	src = strings.TrimSpace(src)
+	expr, err := ParseExpr(src)
+	if err != nil {
+		return err
+	}
+
+	v, err := expr.Eval(contextResolver(ctx))
+	if err != nil {
+		return err
+	}
+
+	b, ok := v.(bool)
+	if !ok {
+		return fmt.Errorf("expression evaluated to %s, expected bool", typeName(v))
+	} else if !b {
+		return fmt.Errorf("expected %s", src)
 	}
 
 	return nil
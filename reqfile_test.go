@@ -0,0 +1,219 @@
+package reql
+
+import (
+	"net/http"
+	"net/url"
+	"testing"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsimple"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/zclconf/go-cty/cty"
+)
+
+func testExchange() *Exchange {
+	u, _ := url.Parse("http://localhost:8080/ping")
+
+	return &Exchange{
+		Request: Request{Method: http.MethodGet, URL: u.String()},
+		HTTPRequest: &http.Request{
+			Method: http.MethodGet,
+			URL:    u,
+			Header: http.Header{},
+		},
+		Response: &http.Response{
+			StatusCode: 200,
+			Status:     "200 OK",
+			Proto:      "HTTP/1.1",
+			Header:     http.Header{"Content-Length": []string{"4"}},
+		},
+		Body: []byte("pong"),
+	}
+}
+
+func TestAssertion_Assert(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{
+			name:    "Native expression holds",
+			expr:    `res.code == 200 && res.body == "pong"`,
+			wantErr: false,
+		},
+		{
+			name:    "Native expression fails",
+			expr:    `res.code != 200`,
+			wantErr: true,
+		},
+		{
+			name:    "Lowercase header name",
+			expr:    `res.headers.content-length > 0`,
+			wantErr: false,
+		},
+		{
+			name:    "Request variables",
+			expr:    `req.method == "GET"`,
+			wantErr: false,
+		},
+		{
+			name:    "Legacy string expression",
+			expr:    `"res.headers.Content-Length > 0 && res.body == 'pong'"`,
+			wantErr: false,
+		},
+		{
+			name:    "Legacy string expression fails",
+			expr:    `"res.code == 404"`,
+			wantErr: true,
+		},
+		{
+			name:    "Non-boolean expression",
+			expr:    `res.headers`,
+			wantErr: true,
+		},
+		{
+			name:    "Native string expression",
+			expr:    `res.body`,
+			wantErr: true,
+		},
+		{
+			name:    "Native template of the response body",
+			expr:    `"${res.body}"`,
+			wantErr: true,
+		},
+		{
+			name:    "Unknown attribute",
+			expr:    `res.nope`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatalf("ParseExpression() unexpected error = %v", diags)
+			}
+
+			legacy, err := checkLegacyAssertion(expr)
+			if err != nil {
+				t.Fatalf("checkLegacyAssertion() unexpected error = %v", err)
+			}
+
+			a := Assertion{Name: tt.name, Expr: expr, source: tt.expr, legacy: legacy}
+			ctx := exchangeContext(newEvalContext(nil, nil, ""), testExchange())
+			if err := a.Assert(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Assertion.Assert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestAssertion_AssertJSON(t *testing.T) {
+	tests := []struct {
+		name    string
+		expr    string
+		wantErr bool
+	}{
+		{
+			name:    "Nested field",
+			expr:    `res.json.items[0].id == 1`,
+			wantErr: false,
+		},
+		{
+			name:    "Length",
+			expr:    `length(res.json.items) == 2 && length(res.json) == 2 && length(res.json.items[0].name) == 1`,
+			wantErr: false,
+		},
+		{
+			name:    "Types",
+			expr:    `typeof(res.json.items) == "array" && typeof(res.json.items[1].name) == "string" && typeof(res.json.next) == "null"`,
+			wantErr: false,
+		},
+		{
+			name:    "Legacy index syntax",
+			expr:    `"res.json.items[1].name == 'b' && res.json['next'] == null"`,
+			wantErr: false,
+		},
+		{
+			name:    "Index out of range",
+			expr:    `res.json.items[2].id == 3`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			expr, diags := hclsyntax.ParseExpression([]byte(tt.expr), "test.hcl", hcl.InitialPos)
+			if diags.HasErrors() {
+				t.Fatalf("ParseExpression() unexpected error = %v", diags)
+			}
+
+			ex := testExchange()
+			ex.Body = []byte(`{"items": [{"id": 1, "name": "a"}, {"id": 2, "name": "b"}], "next": null}`)
+
+			legacy, err := checkLegacyAssertion(expr)
+			if err != nil {
+				t.Fatalf("checkLegacyAssertion() unexpected error = %v", err)
+			}
+
+			a := Assertion{Name: tt.name, Expr: expr, source: tt.expr, legacy: legacy}
+			ctx := exchangeContext(newEvalContext(nil, nil, ""), ex)
+			if err := a.Assert(ctx); (err != nil) != tt.wantErr {
+				t.Errorf("Assertion.Assert() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestJSONValIsNullForInvalidBodies(t *testing.T) {
+	for _, body := range []string{"", "pong", "{"} {
+		if v := jsonVal([]byte(body)); !v.IsNull() {
+			t.Errorf("jsonVal(%q) = %#v, want null", body, v)
+		}
+	}
+}
+
+func TestReqfile_Capture(t *testing.T) {
+	src := []byte(`
+request {
+  method = "GET"
+  url    = "http://localhost:8080/login"
+}
+
+response {}
+
+capture "token" {
+  from = res.json.access_token
+}
+
+capture "code" {
+  from = res.code
+}
+`)
+
+	var reqfile Reqfile
+	if err := hclsimple.Decode("test.hcl", src, newEvalContext(nil, nil, ""), &reqfile); err != nil {
+		t.Fatalf("Decode() unexpected error = %v", err)
+	}
+	reqfile.ctx = newEvalContext(nil, nil, "")
+
+	ex := testExchange()
+	ex.Body = []byte(`{"access_token": "abc"}`)
+
+	vars := NewVars()
+	if err := reqfile.Capture(ex, vars); err != nil {
+		t.Fatalf("Reqfile.Capture() unexpected error = %v", err)
+	}
+
+	if v, ok := vars.Get("token"); !ok || v.AsString() != "abc" {
+		t.Errorf("Reqfile.Capture() token = %#v, want \"abc\"", v)
+	}
+	if v, ok := vars.Get("code"); !ok || !v.RawEquals(cty.NumberIntVal(200)) {
+		t.Errorf("Reqfile.Capture() code = %#v, want 200", v)
+	}
+
+	ctx := newEvalContext(nil, vars, "")
+	if got := ctx.Variables["vars"].GetAttr("token"); got.AsString() != "abc" {
+		t.Errorf("vars.token = %#v, want \"abc\"", got)
+	}
+}
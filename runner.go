@@ -0,0 +1,273 @@
+package reql
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Status summarizes the outcome of running a reqfile.
+type Status int
+
+// The possible outcomes of running a reqfile.
+const (
+	// StatusPassed means the request was sent and every assertion held.
+	StatusPassed Status = iota
+	// StatusFailed means the request was sent but at least one assertion failed.
+	StatusFailed
+	// StatusErrored means the reqfile could not be parsed, sent, or captured from.
+	StatusErrored
+	// StatusSkipped means the reqfile was not sent because a dependency did not pass.
+	StatusSkipped
+)
+
+func (s Status) String() string {
+	switch s {
+	case StatusPassed:
+		return "PASSED"
+	case StatusFailed:
+		return "FAILED"
+	case StatusErrored:
+		return "ERRORED"
+	case StatusSkipped:
+		return "SKIPPED"
+	}
+
+	return fmt.Sprintf("%%!(Status=%d)", s)
+}
+
+// AssertionResult is the outcome of a single assertion. Err is nil when the
+// assertion held.
+type AssertionResult struct {
+	Name string
+	Err  error
+}
+
+// Result is the outcome of running a single reqfile. Exchange is nil if the request
+// was never sent.
+type Result struct {
+	Path       string
+	Exchange   *Exchange
+	Assertions []AssertionResult
+	Err        error
+	Skipped    bool
+}
+
+// Status reports the overall outcome of the result.
+func (r Result) Status() Status {
+	switch {
+	case r.Skipped:
+		return StatusSkipped
+	case r.Err != nil:
+		return StatusErrored
+	}
+
+	for _, a := range r.Assertions {
+		if a.Err != nil {
+			return StatusFailed
+		}
+	}
+
+	return StatusPassed
+}
+
+// Runner sends the reqfiles in a plan.
+type Runner struct {
+	// Client sends the requests.
+	Client *Client
+	// Env holds the values exposed to reqfiles through the env variable.
+	Env Env
+	// Vars holds the session variables. Captures are stored here. If nil, captured
+	// values are discarded.
+	Vars *Vars
+	// Logger, when set, receives progress messages.
+	Logger Logger
+	// Parallel is the maximum number of reqfiles sent at the same time. Values less
+	// than one are treated as one.
+	Parallel int
+}
+
+// Run executes the steps of plan and calls report with the result of each one. A
+// step is skipped when any of its dependencies did not pass. Up to Parallel steps
+// whose dependencies have completed run at the same time. Regardless of the order in
+// which steps finish, report is called from a single goroutine in plan order.
+func (r *Runner) Run(plan *Plan, report func(Result)) {
+	workers := r.Parallel
+	if workers < 1 {
+		workers = 1
+	}
+
+	type completion struct {
+		index  int
+		result Result
+	}
+
+	jobs := make(chan int)
+	done := make(chan completion)
+	defer close(jobs)
+
+	for i := 0; i < workers; i++ {
+		go func() {
+			for index := range jobs {
+				done <- completion{index: index, result: r.RunFile(plan.Steps[index].Path)}
+			}
+		}()
+	}
+
+	s := newSchedule(plan)
+	inflight := 0
+	for s.pending() {
+		for inflight < workers && len(s.ready) > 0 {
+			index := s.pop()
+			if dep, status, ok := s.blocked(index); ok {
+				s.complete(index, Result{
+					Path:    plan.Steps[index].Path,
+					Err:     fmt.Errorf("dependency %s %s", dep, strings.ToLower(status.String())),
+					Skipped: true,
+				}, report)
+				continue
+			}
+
+			inflight++
+			jobs <- index
+		}
+
+		if inflight > 0 {
+			c := <-done
+			inflight--
+			s.complete(c.index, c.result, report)
+		}
+	}
+}
+
+// schedule tracks which steps of a plan are ready to run and which results can be
+// reported.
+type schedule struct {
+	plan       *Plan
+	index      map[string]int
+	remaining  []int
+	dependents [][]int
+	ready      []int
+	results    []*Result
+	reported   int
+}
+
+func newSchedule(plan *Plan) *schedule {
+	s := &schedule{
+		plan:       plan,
+		index:      make(map[string]int, len(plan.Steps)),
+		remaining:  make([]int, len(plan.Steps)),
+		dependents: make([][]int, len(plan.Steps)),
+		results:    make([]*Result, len(plan.Steps)),
+	}
+
+	for i, step := range plan.Steps {
+		s.index[step.Path] = i
+	}
+
+	for i, step := range plan.Steps {
+		for _, dep := range step.DependsOn {
+			s.remaining[i]++
+			s.dependents[s.index[dep]] = append(s.dependents[s.index[dep]], i)
+		}
+
+		if s.remaining[i] == 0 {
+			s.ready = append(s.ready, i)
+		}
+	}
+
+	return s
+}
+
+func (s *schedule) pending() bool {
+	return s.reported < len(s.results)
+}
+
+// pop removes the ready step that comes first in the plan.
+func (s *schedule) pop() int {
+	min := 0
+	for i := range s.ready {
+		if s.ready[i] < s.ready[min] {
+			min = i
+		}
+	}
+
+	index := s.ready[min]
+	s.ready = append(s.ready[:min], s.ready[min+1:]...)
+
+	return index
+}
+
+// blocked reports the first dependency of the step that did not pass.
+func (s *schedule) blocked(index int) (string, Status, bool) {
+	for _, dep := range s.plan.Steps[index].DependsOn {
+		if status := s.results[s.index[dep]].Status(); status != StatusPassed {
+			return dep, status, true
+		}
+	}
+
+	return "", StatusPassed, false
+}
+
+// complete records the result of a step, readies its dependents, and reports every
+// result that is next in plan order.
+func (s *schedule) complete(index int, result Result, report func(Result)) {
+	s.results[index] = &result
+
+	for _, dependent := range s.dependents[index] {
+		s.remaining[dependent]--
+		if s.remaining[dependent] == 0 {
+			s.ready = append(s.ready, dependent)
+		}
+	}
+
+	for s.reported < len(s.results) && s.results[s.reported] != nil {
+		report(*s.results[s.reported])
+		s.reported++
+	}
+}
+
+// RunFile parses, sends, and checks a single reqfile without regard to its
+// dependencies.
+func (r *Runner) RunFile(path string) Result {
+	r.logf("Running %s...\n", path)
+
+	result := Result{Path: path}
+
+	vars := r.Vars
+	if vars == nil {
+		vars = NewVars()
+	}
+
+	reqfile, err := ParseReqfile(path, r.Env, vars)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	for _, name := range reqfile.LegacyAssertions() {
+		r.warnf("%s: assertion %q uses a deprecated string expression, rewrite it as a native HCL expression", path, name)
+	}
+
+	result.Exchange, err = r.Client.Do(reqfile.Request)
+	if err != nil {
+		result.Err = err
+		return result
+	}
+
+	result.Assertions = reqfile.Check(result.Exchange)
+	result.Err = reqfile.Capture(result.Exchange, vars)
+
+	return result
+}
+
+func (r *Runner) logf(format string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Info(format, args...)
+	}
+}
+
+func (r *Runner) warnf(format string, args ...interface{}) {
+	if r.Logger != nil {
+		r.Logger.Warn(format, args...)
+	}
+}
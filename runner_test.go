@@ -0,0 +1,92 @@
+package reql
+
+import (
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestRunner_Run(t *testing.T) {
+	var inflight, maxInflight int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&inflight, 1)
+		defer atomic.AddInt32(&inflight, -1)
+		for {
+			max := atomic.LoadInt32(&maxInflight)
+			if n <= max || atomic.CompareAndSwapInt32(&maxInflight, max, n) {
+				break
+			}
+		}
+
+		time.Sleep(20 * time.Millisecond)
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusInternalServerError)
+		}
+	}))
+	defer srv.Close()
+
+	dir := t.TempDir()
+	reqfiles := map[string]string{
+		"a.hcl": `["fail.hcl"]`,
+		"b.hcl": "",
+		"c.hcl": "",
+		"d.hcl": `["b.hcl"]`,
+		"e.hcl": "",
+	}
+	for name, deps := range reqfiles {
+		path := "/" + name
+		if deps == "" {
+			deps = "[]"
+		}
+		src := fmt.Sprintf(
+			"depends_on = %s\nrequest {\n  method = \"GET\"\n  url = \"%s%s\"\n}\nresponse {\n  assert \"ok\" {\n    expr = res.code == 200\n  }\n}\n",
+			deps, srv.URL, path,
+		)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(src), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	failSrc := fmt.Sprintf("request {\n  method = \"GET\"\n  url = \"%s/fail\"\n}\nresponse {\n  assert \"ok\" {\n    expr = res.code == 200\n  }\n}\n", srv.URL)
+	if err := os.WriteFile(filepath.Join(dir, "fail.hcl"), []byte(failSrc), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	var files []string
+	for _, name := range []string{"a.hcl", "b.hcl", "c.hcl", "d.hcl", "e.hcl"} {
+		files = append(files, filepath.Join(dir, name))
+	}
+
+	plan, err := NewPlan(files, nil)
+	if err != nil {
+		t.Fatalf("NewPlan() unexpected error = %v", err)
+	}
+
+	runner := &Runner{Client: mustNewClient(t), Parallel: 3}
+
+	var got []string
+	var statuses []Status
+	runner.Run(plan, func(result Result) {
+		got = append(got, filepath.Base(result.Path))
+		statuses = append(statuses, result.Status())
+	})
+
+	wantOrder := []string{"fail.hcl", "a.hcl", "b.hcl", "c.hcl", "d.hcl", "e.hcl"}
+	if !reflect.DeepEqual(got, wantOrder) {
+		t.Errorf("Runner.Run() reported %v, want %v", got, wantOrder)
+	}
+
+	wantStatuses := []Status{StatusFailed, StatusSkipped, StatusPassed, StatusPassed, StatusPassed, StatusPassed}
+	if !reflect.DeepEqual(statuses, wantStatuses) {
+		t.Errorf("Runner.Run() statuses = %v, want %v", statuses, wantStatuses)
+	}
+
+	if max := atomic.LoadInt32(&maxInflight); max < 2 || max > 3 {
+		t.Errorf("Runner.Run() sent at most %d requests at once, want between 2 and 3", max)
+	}
+}
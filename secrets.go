@@ -0,0 +1,365 @@
+package reql
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// SecretPrefix marks an env value as a reference to a secret in the secret store,
+// as in password = "secret:db_pass".
+const SecretPrefix = "secret:"
+
+// SecretMask replaces secret values in output.
+const SecretMask = "********"
+
+// ErrSecretNotFound is returned when a secret does not exist in the store.
+var ErrSecretNotFound = errors.New("secret not found")
+
+// SecretStore is a file of named secrets encrypted with AES-256-GCM. The key is
+// read from a separate key file, which is generated the first time a secret is set.
+// The decrypted secrets are cached after the first read. A SecretStore is safe for
+// concurrent use.
+type SecretStore struct {
+	path    string
+	keyPath string
+
+	mu      sync.Mutex
+	secrets map[string]string
+}
+
+// NewSecretStore returns a store that keeps its secrets in the file at path,
+// encrypted with the key in the file at keyPath. Neither file has to exist yet.
+func NewSecretStore(path, keyPath string) *SecretStore {
+	return &SecretStore{path: path, keyPath: keyPath}
+}
+
+// Get returns the value of the named secret.
+func (s *SecretStore) Get(name string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return "", err
+	}
+
+	value, ok := secrets[name]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+
+	return value, nil
+}
+
+// Set stores value under name, replacing any previous value.
+func (s *SecretStore) Set(name, value string) error {
+	if name == "" {
+		return errors.New("secret name required")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	secrets[name] = value
+
+	return s.save(secrets)
+}
+
+// Delete removes the named secret.
+func (s *SecretStore) Delete(name string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return err
+	}
+
+	if _, ok := secrets[name]; !ok {
+		return fmt.Errorf("%w: %s", ErrSecretNotFound, name)
+	}
+	delete(secrets, name)
+
+	return s.save(secrets)
+}
+
+// List returns the sorted names of the stored secrets.
+func (s *SecretStore) List() ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	secrets, err := s.load()
+	if err != nil {
+		return nil, err
+	}
+
+	names := make([]string, 0, len(secrets))
+	for name := range secrets {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names, nil
+}
+
+// load returns the decrypted secrets. A missing store has no secrets.
+func (s *SecretStore) load() (map[string]string, error) {
+	if s.secrets != nil {
+		return s.secrets, nil
+	}
+
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		s.secrets = make(map[string]string)
+		return s.secrets, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	aead, err := s.cipher(false)
+	if err != nil {
+		return nil, err
+	}
+
+	n := aead.NonceSize()
+	if len(data) < n {
+		return nil, fmt.Errorf("%s: truncated secret store", s.path)
+	}
+
+	plain, err := aead.Open(nil, data[:n], data[n:], nil)
+	if err != nil {
+		return nil, fmt.Errorf("%s: cannot decrypt secret store, wrong key?", s.path)
+	}
+
+	var secrets map[string]string
+	if err := json.Unmarshal(plain, &secrets); err != nil {
+		return nil, fmt.Errorf("%s: %w", s.path, err)
+	}
+	if secrets == nil {
+		secrets = make(map[string]string)
+	}
+	s.secrets = secrets
+
+	return secrets, nil
+}
+
+// save encrypts the secrets with a new nonce and writes them to the store.
+func (s *SecretStore) save(secrets map[string]string) error {
+	aead, err := s.cipher(true)
+	if err != nil {
+		return err
+	}
+
+	plain, err := json.Marshal(secrets)
+	if err != nil {
+		return err
+	}
+
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(s.path), 0o700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(s.path, aead.Seal(nonce, nonce, plain, nil), 0o600); err != nil {
+		return err
+	}
+	s.secrets = secrets
+
+	return nil
+}
+
+// cipher returns an AES-GCM cipher using the key in the key file. The key file holds
+// 32 hex encoded bytes. If create is true and the key file does not exist, a new
+// random key is written to it.
+func (s *SecretStore) cipher(create bool) (cipher.AEAD, error) {
+	data, err := os.ReadFile(s.keyPath)
+	if os.IsNotExist(err) && create {
+		data, err = s.newKey()
+	}
+	if err != nil {
+		return nil, fmt.Errorf("secret key: %w", err)
+	}
+
+	key, err := hex.DecodeString(strings.TrimSpace(string(data)))
+	if err != nil || len(key) != 32 {
+		return nil, fmt.Errorf("secret key: %s must hold 32 hex encoded bytes", s.keyPath)
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+
+	return cipher.NewGCM(block)
+}
+
+func (s *SecretStore) newKey() ([]byte, error) {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		return nil, err
+	}
+
+	data := []byte(hex.EncodeToString(key) + "\n")
+	if err := os.MkdirAll(filepath.Dir(s.keyPath), 0o700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(s.keyPath, data, 0o600); err != nil {
+		return nil, err
+	}
+
+	return data, nil
+}
+
+// Secret is an env value that refers to a secret in a SecretStore. It stands in
+// for the secret until the env is resolved by ParseReqfile.
+type Secret struct {
+	Name  string
+	store *SecretStore
+}
+
+// String returns the reference to the secret, never its value.
+func (s Secret) String() string {
+	return SecretPrefix + s.Name
+}
+
+// Value looks up the secret in its store.
+func (s Secret) Value() (string, error) {
+	if s.store == nil {
+		return "", fmt.Errorf("%w: %s", ErrSecretNotFound, s.Name)
+	}
+
+	return s.store.Get(s.Name)
+}
+
+// withSecrets returns a copy of the env in which every string starting with
+// SecretPrefix, including strings nested in tables and arrays, is replaced by a
+// Secret in store.
+func (e Env) withSecrets(store *SecretStore) Env {
+	env := make(Env, len(e))
+	for k, v := range e {
+		env[k], _ = mapEnvValue(v, func(v interface{}) (interface{}, error) {
+			if s, ok := v.(string); ok && strings.HasPrefix(s, SecretPrefix) {
+				return Secret{Name: strings.TrimPrefix(s, SecretPrefix), store: store}, nil
+			}
+			return v, nil
+		})
+	}
+
+	return env
+}
+
+// resolveSecrets returns a copy of the env in which every Secret, including those
+// nested in tables and arrays, is replaced by its value.
+func (e Env) resolveSecrets() (Env, error) {
+	env := make(Env, len(e))
+	for k, v := range e {
+		v, err := mapEnvValue(v, func(v interface{}) (interface{}, error) {
+			if s, ok := v.(Secret); ok {
+				return s.Value()
+			}
+			return v, nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("env %s: %w", k, err)
+		}
+		env[k] = v
+	}
+
+	return env, nil
+}
+
+// SecretValues returns the values of the secrets the env refers to so that they can
+// be masked in output. Secrets that cannot be read are skipped; ParseReqfile reports
+// them when a reqfile is parsed.
+func (e Env) SecretValues() []string {
+	var values []string
+	for _, v := range e {
+		mapEnvValue(v, func(v interface{}) (interface{}, error) {
+			if s, ok := v.(Secret); ok {
+				if value, err := s.Value(); err == nil && value != "" {
+					values = append(values, value)
+				}
+			}
+			return v, nil
+		})
+	}
+
+	return values
+}
+
+// mapEnvValue returns a copy of v in which every value that is not a table or an
+// array is replaced by the result of f. Tables and arrays are walked recursively.
+func mapEnvValue(v interface{}, f func(interface{}) (interface{}, error)) (interface{}, error) {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, item := range v {
+			item, err := mapEnvValue(item, f)
+			if err != nil {
+				return nil, err
+			}
+			m[k] = item
+		}
+		return m, nil
+	case []map[string]interface{}:
+		s := make([]map[string]interface{}, len(v))
+		for i, item := range v {
+			item, err := mapEnvValue(item, f)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = item.(map[string]interface{})
+		}
+		return s, nil
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, item := range v {
+			item, err := mapEnvValue(item, f)
+			if err != nil {
+				return nil, err
+			}
+			s[i] = item
+		}
+		return s, nil
+	}
+
+	return f(v)
+}
+
+// MaskSecrets replaces every occurrence of the secret values in s with SecretMask.
+// Longer values are replaced first so that a secret containing another is fully
+// masked.
+func MaskSecrets(s string, secrets []string) string {
+	if len(secrets) == 0 {
+		return s
+	}
+
+	sorted := append([]string(nil), secrets...)
+	sort.Slice(sorted, func(i, j int) bool { return len(sorted[i]) > len(sorted[j]) })
+
+	for _, secret := range sorted {
+		if secret != "" {
+			s = strings.ReplaceAll(s, secret, SecretMask)
+		}
+	}
+
+	return s
+}
@@ -0,0 +1,160 @@
+package reql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+)
+
+func TestSecretStore(t *testing.T) {
+	dir := t.TempDir()
+	path, keyPath := filepath.Join(dir, ".reqsecrets"), filepath.Join(dir, "keys", "secret.key")
+
+	store := NewSecretStore(path, keyPath)
+	if err := store.Set("db_pass", "hunter2"); err != nil {
+		t.Fatalf("SecretStore.Set() error = %v", err)
+	}
+	if err := store.Set("token", "abc"); err != nil {
+		t.Fatalf("SecretStore.Set() error = %v", err)
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if strings.Contains(string(data), "hunter2") {
+		t.Error("SecretStore.Set() wrote the secret in plaintext")
+	}
+
+	// A new store reads the secrets back from disk.
+	store = NewSecretStore(path, keyPath)
+	if got, err := store.Get("db_pass"); err != nil || got != "hunter2" {
+		t.Errorf("SecretStore.Get() = %q, %v, want %q", got, err, "hunter2")
+	}
+	if _, err := store.Get("missing"); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("SecretStore.Get() error = %v, want ErrSecretNotFound", err)
+	}
+
+	if err := store.Delete("token"); err != nil {
+		t.Fatalf("SecretStore.Delete() error = %v", err)
+	}
+	if got, err := NewSecretStore(path, keyPath).List(); err != nil || !reflect.DeepEqual(got, []string{"db_pass"}) {
+		t.Errorf("SecretStore.List() = %v, %v, want [db_pass]", got, err)
+	}
+
+	other := filepath.Join(dir, "other.key")
+	if err := os.WriteFile(other, []byte(strings.Repeat("ab", 32)), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := NewSecretStore(path, other).Get("db_pass"); err == nil {
+		t.Error("SecretStore.Get() error = nil, want an error for the wrong key")
+	}
+}
+
+func TestParseReqfile_Secrets(t *testing.T) {
+	dir := t.TempDir()
+	config := "secret_key_file = 'secret.key'\n\n[environments.local]\npassword = 'secret:db_pass'\n"
+	if err := os.WriteFile(filepath.Join(dir, ".reqrc"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseConfig(filepath.Join(dir, ".reqrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SecretStore().Set("db_pass", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := c.LoadEnv("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got := env.SecretValues(); !reflect.DeepEqual(got, []string{"hunter2"}) {
+		t.Errorf("Env.SecretValues() = %v, want [hunter2]", got)
+	}
+
+	path := writeReqfile(t, "request {\n  method = \"POST\"\n  url = \"http://localhost\"\n  body = env.password\n}\nresponse {}\n")
+	reqfile, err := ParseReqfile(path, env, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+	if reqfile.Request.Body != "hunter2" {
+		t.Errorf("ParseReqfile() Body = %q, want %q", reqfile.Request.Body, "hunter2")
+	}
+
+	if err := c.SecretStore().Delete("db_pass"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := ParseReqfile(path, env, nil); !errors.Is(err, ErrSecretNotFound) {
+		t.Errorf("ParseReqfile() error = %v, want ErrSecretNotFound", err)
+	}
+}
+
+func TestParseReqfile_NestedSecrets(t *testing.T) {
+	dir := t.TempDir()
+	config := `secret_key_file = 'secret.key'
+
+[environments.local]
+auth = { user = "alice", password = "secret:db_pass" }
+tokens = ["plain", "secret:token"]
+`
+	if err := os.WriteFile(filepath.Join(dir, ".reqrc"), []byte(config), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	c, err := ParseConfig(filepath.Join(dir, ".reqrc"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SecretStore().Set("db_pass", "hunter2"); err != nil {
+		t.Fatal(err)
+	}
+	if err := c.SecretStore().Set("token", "abc123"); err != nil {
+		t.Fatal(err)
+	}
+
+	env, err := c.LoadEnv("local")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got := env.SecretValues()
+	sort.Strings(got)
+	if want := []string{"abc123", "hunter2"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Env.SecretValues() = %v, want %v", got, want)
+	}
+
+	path := writeReqfile(t, "request {\n  method = \"POST\"\n  url = \"http://localhost\"\n  body = \"${env.auth.password} ${env.tokens[0]} ${env.tokens[1]}\"\n}\nresponse {}\n")
+	reqfile, err := ParseReqfile(path, env, nil)
+	if err != nil {
+		t.Fatalf("ParseReqfile() unexpected error = %v", err)
+	}
+	if want := "hunter2 plain abc123"; reqfile.Request.Body != want {
+		t.Errorf("ParseReqfile() Body = %q, want %q", reqfile.Request.Body, want)
+	}
+}
+
+func TestMaskSecrets(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		secrets []string
+		want    string
+	}{
+		{name: "No secrets", s: "token=abc", want: "token=abc"},
+		{name: "Every occurrence", s: "abc and abc", secrets: []string{"abc"}, want: "******** and ********"},
+		{name: "Longest first", s: "abcdef", secrets: []string{"abc", "abcdef"}, want: "********"},
+		{name: "Empty secret", s: "abc", secrets: []string{""}, want: "abc"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := MaskSecrets(tt.s, tt.secrets); got != tt.want {
+				t.Errorf("MaskSecrets() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,110 @@
+package reql
+
+import (
+	"crypto/tls"
+	"fmt"
+	"net/http/httptrace"
+	"sync"
+	"time"
+)
+
+// Timing breaks down where the time of an exchange was spent. DNS, Connect, and
+// TLSHandshake are zero when a pooled connection was reused. When redirects are
+// followed, the phases of every request are summed.
+type Timing struct {
+	// DNS is the time spent resolving the host name.
+	DNS time.Duration
+	// Connect is the time spent establishing the TCP connection.
+	Connect time.Duration
+	// TLSHandshake is the time spent on the TLS handshake.
+	TLSHandshake time.Duration
+	// TimeToFirstByte is the time from sending the request until the first byte of
+	// the final response arrived.
+	TimeToFirstByte time.Duration
+	// Total is the time from sending the request until the response body was read.
+	Total time.Duration
+}
+
+func (t Timing) String() string {
+	return fmt.Sprintf(
+		"dns=%s connect=%s tls=%s ttfb=%s total=%s",
+		t.DNS.Round(time.Microsecond),
+		t.Connect.Round(time.Microsecond),
+		t.TLSHandshake.Round(time.Microsecond),
+		t.TimeToFirstByte.Round(time.Microsecond),
+		t.Total.Round(time.Microsecond),
+	)
+}
+
+// Millis returns d in milliseconds with microsecond precision, as reported by
+// res.time_ms, res.timing, and the JSON output.
+func Millis(d time.Duration) float64 {
+	return float64(d.Microseconds()) / 1000
+}
+
+// timingTrace collects a Timing through httptrace callbacks. The callbacks may be
+// called from multiple goroutines.
+type timingTrace struct {
+	mu     sync.Mutex
+	start  time.Time
+	timing Timing
+
+	dnsStart, connectStart, tlsStart time.Time
+}
+
+func newTimingTrace() *timingTrace {
+	return &timingTrace{start: time.Now()}
+}
+
+func (t *timingTrace) clientTrace() *httptrace.ClientTrace {
+	return &httptrace.ClientTrace{
+		DNSStart: func(httptrace.DNSStartInfo) {
+			t.mark(&t.dnsStart)
+		},
+		DNSDone: func(httptrace.DNSDoneInfo) {
+			t.record(&t.timing.DNS, &t.dnsStart)
+		},
+		ConnectStart: func(string, string) {
+			t.mark(&t.connectStart)
+		},
+		ConnectDone: func(string, string, error) {
+			t.record(&t.timing.Connect, &t.connectStart)
+		},
+		TLSHandshakeStart: func() {
+			t.mark(&t.tlsStart)
+		},
+		TLSHandshakeDone: func(tls.ConnectionState, error) {
+			t.record(&t.timing.TLSHandshake, &t.tlsStart)
+		},
+		GotFirstResponseByte: func() {
+			t.mu.Lock()
+			defer t.mu.Unlock()
+
+			t.timing.TimeToFirstByte = time.Since(t.start)
+		},
+	}
+}
+
+func (t *timingTrace) mark(start *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	*start = time.Now()
+}
+
+// record adds the time since the phase started to d.
+func (t *timingTrace) record(d *time.Duration, start *time.Time) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	*d += time.Since(*start)
+}
+
+// finish returns the collected timing with the total measured up to now.
+func (t *timingTrace) finish() Timing {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.timing.Total = time.Since(t.start)
+	return t.timing
+}
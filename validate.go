@@ -0,0 +1,226 @@
+package reql
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/BurntSushi/toml"
+)
+
+// legacyConfigFileName is the name the config file had in earlier versions.
+const legacyConfigFileName = ".reqlrc"
+
+// ConfigError describes a problem with a config file. Line is 1-based and is 0 when
+// the problem cannot be tied to a line.
+type ConfigError struct {
+	Path string
+	Line int
+	Msg  string
+}
+
+func (e *ConfigError) Error() string {
+	if e.Line == 0 {
+		return fmt.Sprintf("%s: %s", e.Path, e.Msg)
+	}
+
+	return fmt.Sprintf("%s:%d: %s", e.Path, e.Line, e.Msg)
+}
+
+// parseError converts an error from decoding the config file at path to a
+// ConfigError if it has a position.
+func parseError(path string, err error) error {
+	var perr toml.ParseError
+	if errors.As(err, &perr) {
+		// The message is only exported for some errors, so strip the position from
+		// the formatted error instead.
+		msg := strings.TrimPrefix(perr.Error(), fmt.Sprintf("toml: line %d", perr.Position.Line))
+		if perr.LastKey != "" {
+			msg = strings.TrimPrefix(msg, fmt.Sprintf(" (last key %q)", perr.LastKey))
+		}
+
+		return &ConfigError{Path: path, Line: perr.Position.Line, Msg: strings.TrimPrefix(msg, ": ")}
+	}
+
+	return fmt.Errorf("%s: %w", path, err)
+}
+
+// unknownKeys returns an error for every key of the config file at path that does
+// not match a setting. The contents of envs are free-form, so keys nested in an env
+// are never unknown.
+func unknownKeys(path string, src []byte, md toml.MetaData) []*ConfigError {
+	var errs []*ConfigError
+	for _, key := range md.Undecoded() {
+		if len(key) > 2 && key[0] == "environments" {
+			continue
+		}
+
+		errs = append(errs, &ConfigError{
+			Path: path,
+			Line: keyLine(src, key...),
+			Msg:  fmt.Sprintf("unknown key %s", key),
+		})
+	}
+
+	return errs
+}
+
+// Validate checks the merged config for problems that would make it misbehave:
+// unknown keys, an undefined default env, envs that extend unknown envs or each
+// other in a cycle, aliases and files that do not exist, and a root that is not a
+// directory. Each problem points at the file and line of the setting at fault.
+func (c *Config) Validate() []*ConfigError {
+	errs := append([]*ConfigError(nil), c.unknown...)
+
+	lines := make(map[string][]byte)
+	report := func(key []string, format string, args ...interface{}) {
+		setting := key[0]
+		if len(key) > 1 {
+			setting += "." + key[1]
+		}
+
+		path := c.sources[setting]
+		if path == "" {
+			path = c.path
+		}
+
+		src, ok := lines[path]
+		if !ok {
+			src, _ = os.ReadFile(path)
+			lines[path] = src
+		}
+
+		errs = append(errs, &ConfigError{Path: path, Line: keyLine(src, key...), Msg: fmt.Sprintf(format, args...)})
+	}
+
+	if len(c.files) == 0 || c.files[len(c.files)-1] != c.path {
+		legacy := filepath.Join(c.dir, legacyConfigFileName)
+		if _, err := os.Stat(legacy); err == nil {
+			errs = append(errs, &ConfigError{
+				Path: legacy,
+				Msg:  fmt.Sprintf("config files are named %s, rename this file to use it", ConfigFileName),
+			})
+		}
+	}
+
+	if c.DefaultEnv != "" {
+		if _, ok := c.Environments[c.DefaultEnv]; !ok {
+			report([]string{"default_env"}, "default_env %s is not defined in environments", c.DefaultEnv)
+		}
+	}
+
+	if c.Root != "" {
+		if info, err := os.Stat(c.Root); err != nil {
+			report([]string{"root"}, "root %s does not exist", c.Root)
+		} else if !info.IsDir() {
+			report([]string{"root"}, "root %s is not a directory", c.Root)
+		}
+	}
+
+	for _, name := range sortedStringKeys(c.Aliases) {
+		path, _ := SplitRequestRef(c.Aliases[name])
+		if _, err := os.Stat(path); err != nil {
+			report([]string{"aliases", name}, "alias %s points to %s, which does not exist", name, path)
+		}
+	}
+
+	names := make([]string, 0, len(c.Environments))
+	for name := range c.Environments {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		env := c.Environments[name]
+
+		if _, err := c.EnvLayers(name); err != nil {
+			report([]string{"environments", name, ExtendsKey}, "%v", err)
+		}
+
+		if v, ok := env[EnvFileKey]; ok {
+			if path, ok := v.(string); !ok {
+				report([]string{"environments", name, EnvFileKey}, "env %s: %s must be a string", name, EnvFileKey)
+			} else {
+				if !filepath.IsAbs(path) {
+					path = filepath.Join(c.envDir(name), path)
+				}
+				if _, err := os.Stat(path); err != nil {
+					report([]string{"environments", name, EnvFileKey}, "env %s: env file %s does not exist", name, path)
+				}
+			}
+		}
+	}
+
+	client := []struct {
+		key  string
+		path string
+	}{
+		{"ca_file", c.Client.CAFile},
+		{"cert_file", c.Client.CertFile},
+		{"key_file", c.Client.KeyFile},
+	}
+	for _, file := range client {
+		if file.path == "" {
+			continue
+		}
+		if _, err := os.Stat(file.path); err != nil {
+			report([]string{"client", file.key}, "client %s %s does not exist", file.key, file.path)
+		}
+	}
+
+	return errs
+}
+
+// keyLine returns the line of src that defines key, or of its nearest parent that is
+// defined on a line, such as the line of an inline table. It returns 0 if neither
+// is found.
+//
+// The TOML decoder does not record where keys are defined, so the lines are found by
+// scanning src for table headers and key = value lines, including dotted and quoted
+// keys. Positions are best-effort: keys inside multiline values and array tables may
+// be attributed to the wrong line or to none.
+func keyLine(src []byte, key ...string) int {
+	doc := newTomlDoc(src)
+
+	for n := len(key); n > 0; n-- {
+		var table []string
+		for i, line := range doc.lines {
+			if strings.HasPrefix(strings.TrimSpace(line), "[[") {
+				table = nil
+				continue
+			} else if keys, ok := parseTableHeader(line); ok {
+				table = keys
+				if reflect.DeepEqual(keys, key[:n]) {
+					return i + 1
+				}
+				continue
+			}
+
+			keys, ok := parseKeyPath(line)
+			if !ok {
+				continue
+			}
+
+			path := append(append([]string(nil), table...), keys...)
+			if reflect.DeepEqual(path, key[:n]) || (len(path) > n && reflect.DeepEqual(path[:n], key[:n]) && len(keys) > 1) {
+				return i + 1
+			}
+		}
+	}
+
+	return 0
+}
+
+func sortedStringKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	return keys
+}
@@ -0,0 +1,189 @@
+package reql
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestConfig_Validate(t *testing.T) {
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	tests := []struct {
+		name   string
+		config string
+		files  []string
+		want   []string
+	}{
+		{
+			name: "Valid config",
+			config: `root = "requests"
+default_env = "local"
+
+[aliases]
+ping = "requests/ping.hcl"
+
+[environments.base]
+env_file = ".env"
+
+[environments.local]
+extends = "base"
+`,
+			files: []string{"requests/ping.hcl", ".env"},
+		},
+		{
+			name: "Unknown keys",
+			config: `timout = "1s"
+
+[client]
+timeout = "1s"
+proxi = "http://localhost:3128"
+`,
+			want: []string{
+				".reqrc:1: unknown key timout",
+				".reqrc:5: unknown key client.proxi",
+			},
+		},
+		{
+			name: "Nested env values",
+			config: `[environments.local]
+nested = { list = [1, 2], auth = { user = "me" } }
+
+[environments.local.db]
+host = "a"
+port = 5432
+
+[environments.local.db.pool]
+size = 4
+`,
+		},
+		{
+			name: "Undefined default env",
+			config: `default_env = "local"
+
+[environments.prod]
+`,
+			want: []string{".reqrc:1: default_env local is not defined in environments"},
+		},
+		{
+			name: "Dangling alias",
+			config: `[aliases]
+ping = "requests/ping.hcl"
+login = "requests/session.hcl#login"
+`,
+			files: []string{"requests/ping.hcl"},
+			want:  []string{".reqrc:3: alias login points to requests/session.hcl, which does not exist"},
+		},
+		{
+			name: "Bad paths",
+			config: `root = "requests"
+
+[environments.local]
+env_file = ".env"
+
+[client]
+ca_file = "ca.pem"
+`,
+			want: []string{
+				".reqrc:1: root requests does not exist",
+				".reqrc:4: env local: env file .env does not exist",
+				".reqrc:7: client ca_file ca.pem does not exist",
+			},
+		},
+		{
+			name: "Unknown parent env",
+			config: `[environments.local]
+extends = "base"
+`,
+			want: []string{".reqrc:2: env local: extends unknown env base"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := writeTree(t, tt.files...)
+			if err := os.WriteFile(filepath.Join(dir, ".reqrc"), []byte(tt.config), 0o644); err != nil {
+				t.Fatal(err)
+			}
+
+			// Run from the config directory so that reported paths are short.
+			wd, err := os.Getwd()
+			if err != nil {
+				t.Fatal(err)
+			}
+			if err := os.Chdir(dir); err != nil {
+				t.Fatal(err)
+			}
+			t.Cleanup(func() { os.Chdir(wd) })
+
+			c, err := LoadConfig("")
+			if err != nil {
+				t.Fatalf("LoadConfig() error = %v", err)
+			}
+
+			var got []string
+			for _, err := range c.Validate() {
+				got = append(got, err.Error())
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("Config.Validate() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestParseConfig_SyntaxError(t *testing.T) {
+	path := filepath.Join(t.TempDir(), ".reqrc")
+	if err := os.WriteFile(path, []byte("root = \"requests\"\ndefault_env = \"local\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	_, err := ParseConfig(path)
+
+	var cerr *ConfigError
+	if !errors.As(err, &cerr) {
+		t.Fatalf("ParseConfig() error = %v, want a *ConfigError", err)
+	}
+	if cerr.Path != path || cerr.Line != 2 {
+		t.Errorf("ParseConfig() error at %s:%d, want %s:2", cerr.Path, cerr.Line, path)
+	}
+}
+
+func TestKeyLine(t *testing.T) {
+	src := []byte(`timeout = "1s"
+client.timeout = "2s"
+"default_env" = "local"
+
+[environments.local]
+'base url' = "http://localhost"
+timeout = "3s"
+auth = { user = "me" }
+
+[environments."prod"]
+timeout = "4s"
+`)
+
+	tests := []struct {
+		name string
+		key  []string
+		want int
+	}{
+		{name: "Top-level key", key: []string{"timeout"}, want: 1},
+		{name: "Dotted key", key: []string{"client", "timeout"}, want: 2},
+		{name: "Parent of a dotted key", key: []string{"client"}, want: 2},
+		{name: "Quoted key", key: []string{"default_env"}, want: 3},
+		{name: "Table header", key: []string{"environments", "local"}, want: 5},
+		{name: "Quoted key with a space", key: []string{"environments", "local", "base url"}, want: 6},
+		{name: "Key repeated in another table", key: []string{"environments", "prod", "timeout"}, want: 11},
+		{name: "Key in an inline table", key: []string{"environments", "local", "auth", "user"}, want: 8},
+		{name: "Missing key", key: []string{"aliases", "ping"}, want: 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := keyLine(src, tt.key...); got != tt.want {
+				t.Errorf("keyLine() = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,81 @@
+package reql
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/zclconf/go-cty/cty"
+)
+
+// Vars is a session scope of values captured from responses. Captured values are
+// exposed to later reqfiles through the vars variable. Vars is safe for concurrent
+// use.
+type Vars struct {
+	mu     sync.RWMutex
+	values map[string]cty.Value
+}
+
+// NewVars constructs an empty variable scope.
+func NewVars() *Vars {
+	return &Vars{values: make(map[string]cty.Value)}
+}
+
+// Set stores value under name, replacing any previous value.
+func (v *Vars) Set(name string, value cty.Value) {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.values[name] = value
+}
+
+// Get returns the value stored under name.
+func (v *Vars) Get(name string) (cty.Value, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	value, ok := v.values[name]
+	return value, ok
+}
+
+// Names returns the names of all stored values in sorted order.
+func (v *Vars) Names() []string {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	names := make([]string, 0, len(v.values))
+	for name := range v.values {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return names
+}
+
+// Clear removes every stored value.
+func (v *Vars) Clear() {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	v.values = make(map[string]cty.Value)
+}
+
+// object returns the scope as a cty object. A nil scope yields an empty object.
+func (v *Vars) object() cty.Value {
+	if v == nil {
+		return cty.EmptyObjectVal
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	if len(v.values) == 0 {
+		return cty.EmptyObjectVal
+	}
+
+	values := make(map[string]cty.Value, len(v.values))
+	for name, value := range v.values {
+		values[name] = value
+	}
+
+	return cty.ObjectVal(values)
+}